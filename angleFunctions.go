@@ -0,0 +1,127 @@
+package gojacego
+
+import "math"
+
+/*
+	AngleUnit is the unit of the angles given to and returned by the trigonometric functions.
+*/
+type AngleUnit int
+
+const (
+	Radians AngleUnit = iota
+	// a full turn is 360 degrees
+	Degrees
+	// a full turn is 400 gradians
+	Gradians
+)
+
+// The size of a full turn in the unit.
+func (unit AngleUnit) fullTurn() float64 {
+	switch unit {
+	case Degrees:
+		return 360
+	case Gradians:
+		return 400
+	default:
+		return 2 * math.Pi
+	}
+}
+
+func (unit AngleUnit) toRadians(angle float64) float64 {
+	if unit == Radians {
+		return angle
+	}
+	return angle * (2 * math.Pi / unit.fullTurn())
+}
+
+func (unit AngleUnit) fromRadians(angle float64) float64 {
+	if unit == Radians {
+		return angle
+	}
+	return angle * (unit.fullTurn() / (2 * math.Pi))
+}
+
+func (unit AngleUnit) fromDegrees(angle float64) float64 {
+	if unit == Degrees {
+		return angle
+	}
+	return angle / 360 * unit.fullTurn()
+}
+
+/*
+	Returns the sine and the cosine of [angle], in a unit other than radians. They are exact at the
+	multiples of a quarter turn (i.e. 'cos(90)' is 0 in degrees).
+*/
+func (unit AngleUnit) sincos(angle float64) (float64, float64) {
+	quarters := angle / (unit.fullTurn() / 4)
+	if quarters == math.Trunc(quarters) && !math.IsInf(quarters, 0) {
+		switch (int(math.Mod(quarters, 4)) + 4) % 4 {
+		case 0:
+			return 0, 1
+		case 1:
+			return 1, 0
+		case 2:
+			return 0, -1
+		default:
+			return -1, 0
+		}
+	}
+
+	// the angle is reduced in its own unit, which is exact, before being converted
+	return math.Sincos(unit.toRadians(math.Mod(angle, unit.fullTurn())))
+}
+
+/*
+	Registers the trigonometric functions, which take and return angles in [unit], and the
+	conversions between radians and degrees.
+*/
+func registryTrigonometricFunctions(registry *functionRegistry, unit AngleUnit) {
+
+	sin, cos, tan := math.Sin, math.Cos, math.Tan
+	if unit != Radians {
+		sin = func(x float64) float64 {
+			sin, _ := unit.sincos(x)
+			return sin
+		}
+		cos = func(x float64) float64 {
+			_, cos := unit.sincos(x)
+			return cos
+		}
+		tan = func(x float64) float64 {
+			sin, cos := unit.sincos(x)
+			return sin / cos
+		}
+	}
+
+	for name, function := range map[string]func(float64) float64{
+		"sin":  sin,
+		"cos":  cos,
+		"tan":  tan,
+		"asin": func(x float64) float64 { return unit.fromRadians(math.Asin(x)) },
+		"acos": func(x float64) float64 { return unit.fromRadians(math.Acos(x)) },
+		"atan": func(x float64) float64 { return unit.fromRadians(math.Atan(x)) },
+	} {
+		function := function
+		registry.registerTypedFunction(name, func(arguments []float64) (float64, error) {
+			return function(arguments[0]), nil
+		}, 1, 1, false, true)
+	}
+
+	// defined like in Jace.NET
+	for name, function := range map[string]func(float64) float64{
+		"cot":  func(x float64) float64 { return 1 / tan(x) },
+		"sec":  func(x float64) float64 { return 1 / cos(x) },
+		"csc":  func(x float64) float64 { return 1 / sin(x) },
+		"acot": func(x float64) float64 { return unit.fromRadians(math.Atan(1 / x)) },
+		"asec": func(x float64) float64 { return unit.fromRadians(math.Acos(1 / x)) },
+		"acsc": func(x float64) float64 { return unit.fromRadians(math.Asin(1 / x)) },
+		// rad(x) converts x degrees to radians and deg(x) x radians to degrees, whatever the unit
+		"rad": func(x float64) float64 { return x * math.Pi / 180 },
+		"deg": func(x float64) float64 { return x * 180 / math.Pi },
+	} {
+		function := function
+		registry.registerTypedFunction(name, func(arguments []float64) (float64, error) {
+			return function(arguments[0]), nil
+		}, 1, 1, true, true)
+	}
+}
@@ -10,21 +10,31 @@ import (
 
 var precedences = map[rune]int{
 	'(': 0,
-	'&': 1,
-	'|': 1,
+	'∧': 1,
+	'∨': 1,
 	'<': 2,
 	'>': 2,
 	'≤': 2,
 	'≥': 2,
 	'≠': 2,
 	'=': 2,
-	'+': 3,
-	'-': 3,
-	'*': 4,
-	'/': 4,
-	'%': 4,
-	'_': 5,
-	'^': 6,
+	'⧺': 3,
+	'|': 4,
+	'⊕': 5,
+	'&': 6,
+	'≪': 7,
+	'≫': 7,
+	'+': 8,
+	'-': 8,
+	'*': 9,
+	'/': 9,
+	'%': 9,
+	'⫽': 9,
+	'_': 10,
+	'~': 10,
+	'^': 11,
+	'!': 12,
+	'％': 12,
 }
 
 type astBuilder struct {
@@ -35,6 +45,16 @@ type astBuilder struct {
 	constantRegistry         *constantRegistry
 	compiledConstantRegistry *constantRegistry
 	functionRegistry         *functionRegistry
+	maxNestingDepth          int
+	maxOperationDepth        int
+	implicitMultiplication   bool
+	operationDepths          *operationDepths
+}
+
+// Tracks how deep the operations and the functions are nested in the tree built so far.
+type operationDepths struct {
+	depths       map[operation]int
+	operandDepth int
 }
 
 func newAstBuilder(caseSensitive bool, functionRegistry *functionRegistry, constantRegistry *constantRegistry, compiledConstantRegistry *constantRegistry) *astBuilder {
@@ -51,6 +71,7 @@ func newAstBuilder(caseSensitive bool, functionRegistry *functionRegistry, const
 		constantRegistry:         constantRegistry,
 		functionRegistry:         functionRegistry,
 		compiledConstantRegistry: compiledConstantRegistry,
+		operationDepths:          &operationDepths{depths: map[operation]int{}},
 	}
 }
 
@@ -67,16 +88,18 @@ func (this astBuilder) popOperations(untilLeftBracket bool, currentToken *token)
 
 		switch token.Type {
 		case tt_OPERATION:
-			t, err := this.convertOperation(token)
-			if err == nil {
-				this.resultStack.Push(t)
+			t, err := this.convertNestedOperation(token)
+			if err != nil {
+				return err
 			}
+			this.resultStack.Push(t)
 			break
 		case tt_TEXT:
-			f, err := this.convertFunction(token)
-			if err == nil {
-				this.resultStack.Push(f)
+			f, err := this.convertNestedOperation(token)
+			if err != nil {
+				return err
 			}
+			this.resultStack.Push(f)
 			break
 		}
 	}
@@ -85,13 +108,20 @@ func (this astBuilder) popOperations(untilLeftBracket bool, currentToken *token)
 		if this.operatorStack.Len() > 0 && this.operatorStack.Peek().(token).Type == tt_LEFT_BRACKET {
 			this.operatorStack.Pop()
 		} else {
-			return errors.New(fmt.Sprintf("No matching left bracket found for the right "+
-				"bracket at position %d.", currentToken.StartPosition))
+			return &SyntaxError{Code: ErrorCodeMissingLeftBracket,
+				Message:  "no matching left bracket found for the right bracket",
+				Token:    tokenText(*currentToken),
+				Position: currentToken.StartPosition,
+				Length:   currentToken.Length}
 		}
 	} else {
 		if this.operatorStack.Len() > 0 && this.operatorStack.Peek().(token).Type == tt_LEFT_BRACKET && !(currentToken != nil && currentToken.Type == tt_ARGUMENT_SEPARATOR) {
-			return errors.New(fmt.Sprintf("No matching right bracket found for the left "+
-				"bracket at position %d.", this.operatorStack.Peek().(token).StartPosition))
+			leftBracket := this.operatorStack.Peek().(token)
+			return &SyntaxError{Code: ErrorCodeMissingRightBracket,
+				Message:  "no matching right bracket found for the left bracket",
+				Token:    tokenText(leftBracket),
+				Position: leftBracket.StartPosition,
+				Length:   leftBracket.Length}
 		}
 	}
 
@@ -99,130 +129,273 @@ func (this astBuilder) popOperations(untilLeftBracket bool, currentToken *token)
 }
 
 func (this astBuilder) verifyResult() error {
-	if this.resultStack.Len() > 1 {
-		return errors.New("The syntax of the provided formula is not valid.")
+	if this.resultStack.Len() != 1 {
+		return &SyntaxError{Code: ErrorCodeUnexpectedToken, Message: "The syntax of the provided formula is not valid."}
 	}
 	return nil
 }
 
+func (this astBuilder) popOperand(operationToken token) (operation, error) {
+	if this.resultStack.Len() == 0 {
+		return nil, newMissingOperandError(operationToken)
+	}
+	operand := this.resultStack.Pop().(operation)
+	if depth := this.operationDepths.depths[operand]; depth > this.operationDepths.operandDepth {
+		this.operationDepths.operandDepth = depth
+	}
+	return operand, nil
+}
+
 func (this astBuilder) convertFunction(operationToken token) (operation, error) {
 
 	functionName := operationToken.Value.(string)
 
-	if item, found := this.functionRegistry.get(functionName); found {
+	item, found := this.functionRegistry.get(functionName)
+	if !found {
+		return nil, newUnknownFunctionError(operationToken)
+	}
 
-		var numberOfParameters int
-		if true {
-			numberOfParameters = this.parameterCount.Pop().(int)
-		} else {
-			// fixed parameter
-		}
+	// verified by the syntax checker at the right bracket
+	numberOfParameters := this.parameterCount.Pop().(int)
 
-		operations := make([]operation, numberOfParameters)
-		for i := 0; i < numberOfParameters; i++ {
-			operations[i] = this.resultStack.Pop().(operation)
+	operations := make([]operation, numberOfParameters)
+	for i := numberOfParameters - 1; i >= 0; i-- {
+		argument, err := this.popOperand(operationToken)
+		if err != nil {
+			return nil, err
 		}
+		operations[i] = argument
+	}
 
-		// vscode reverse
-		for i, j := 0, len(operations)-1; i < j; i, j = i+1, j-1 {
-			operations[i], operations[j] = operations[j], operations[i]
-		}
+	dataType := floatingPoint
+	if item.isInteger {
+		dataType = integer
+	}
+
+	functionOperation := newFunctionOperation(dataType, functionName, operations, item.isIdempotent)
+	functionOperation.Position = operationToken.StartPosition
+	functionOperation.Length = operationToken.Length
+
+	return functionOperation, nil
+}
 
-		return newFunctionOperation(floatingPoint, functionName, operations, item.isIdempotent), nil
+// Converts the operator or the function, rejecting the operations nested deeper than the limit.
+func (this astBuilder) convertNestedOperation(operationToken token) (operation, error) {
+	convert := this.convertOperation
+	if operationToken.Type == tt_TEXT {
+		convert = this.convertFunction
 	}
 
-	return nil, nil
+	if this.maxOperationDepth == 0 {
+		return convert(operationToken)
+	}
+
+	this.operationDepths.operandDepth = 0
+	op, err := convert(operationToken)
+	if err != nil {
+		return nil, err
+	}
+
+	depth := this.operationDepths.operandDepth + 1
+	if depth > this.maxOperationDepth {
+		return nil, &MaxOperationDepthError{Position: operationToken.StartPosition, Limit: this.maxOperationDepth}
+	}
+	this.operationDepths.depths[op] = depth
+	return op, nil
 }
 
 func (this astBuilder) convertOperation(operationToken token) (operation, error) {
 
-	var dataType operationDataType
-	var argument1 operation
-	var argument2 operation
-	var divisor operation
-	var divident operation
+	operator := rune(operationToken.Value.(int32))
+
+	if operator == '!' || operator == '％' {
+		argument, err := this.popOperand(operationToken)
+		if err != nil {
+			return nil, err
+		}
+		if operator == '!' {
+			factorialOperation := newFactorialOperation(argument.OperationMetadata().DataType, argument)
+			factorialOperation.Position = operationToken.StartPosition
+			factorialOperation.Length = operationToken.Length
+			return factorialOperation, nil
+		}
+		percentageOperation := newPercentageOperation(floatingPoint, argument)
+		percentageOperation.Position = operationToken.StartPosition
+		percentageOperation.Length = operationToken.Length
+		return percentageOperation, nil
+	}
+
+	if operator == '_' {
+		argument, err := this.popOperand(operationToken)
+		if err != nil {
+			return nil, err
+		}
+		unaryMinusOperation := newUnaryMinusOperation(argument.OperationMetadata().DataType, argument)
+		unaryMinusOperation.Position = operationToken.StartPosition
+		unaryMinusOperation.Length = operationToken.Length
+		return unaryMinusOperation, nil
+	}
+
+	if operator == '~' {
+		argument, err := this.popOperand(operationToken)
+		if err != nil {
+			return nil, err
+		}
+		bitwiseNotOperation := newBitwiseNotOperation(integer, argument)
+		bitwiseNotOperation.Position = operationToken.StartPosition
+		bitwiseNotOperation.Length = operationToken.Length
+		return bitwiseNotOperation, nil
+	}
 
-	switch rune(operationToken.Value.(int32)) {
+	argument2, err := this.popOperand(operationToken)
+	if err != nil {
+		return nil, err
+	}
+
+	argument1, err := this.popOperand(operationToken)
+	if err != nil {
+		return nil, err
+	}
+
+	dataType := requiredDataType(argument1, argument2)
+
+	switch operator {
 	case '+':
-		argument2 = this.resultStack.Pop().(operation)
-		argument1 = this.resultStack.Pop().(operation)
-		dataType = requiredDataType(argument1, argument2)
-		return newAddOperation(dataType, argument1, argument2), nil
+		addOperation := newAddOperation(dataType, argument1, argument2)
+		addOperation.Position = operationToken.StartPosition
+		addOperation.Length = operationToken.Length
+		return addOperation, nil
 	case '-':
-		argument2 = this.resultStack.Pop().(operation)
-		argument1 = this.resultStack.Pop().(operation)
-		dataType = requiredDataType(argument1, argument2)
-		return newSubtractionOperation(dataType, argument1, argument2), nil
+		subtractionOperation := newSubtractionOperation(dataType, argument1, argument2)
+		subtractionOperation.Position = operationToken.StartPosition
+		subtractionOperation.Length = operationToken.Length
+		return subtractionOperation, nil
 	case '*':
-		argument2 = this.resultStack.Pop().(operation)
-		argument1 = this.resultStack.Pop().(operation)
-		dataType = requiredDataType(argument1, argument2)
-		return newMultiplicationOperation(dataType, argument1, argument2), nil
+		multiplicationOperation := newMultiplicationOperation(dataType, argument1, argument2)
+		multiplicationOperation.Position = operationToken.StartPosition
+		multiplicationOperation.Length = operationToken.Length
+		return multiplicationOperation, nil
 	case '/':
-		divisor = this.resultStack.Pop().(operation)
-		divident = this.resultStack.Pop().(operation)
-		return newDivisorOperation(floatingPoint, divident, divisor), nil
+		divisorOperation := newDivisorOperation(floatingPoint, argument1, argument2)
+		divisorOperation.Position = operationToken.StartPosition
+		divisorOperation.Length = operationToken.Length
+		return divisorOperation, nil
 	case '%':
-		divisor = this.resultStack.Pop().(operation)
-		divident = this.resultStack.Pop().(operation)
-		return newModuloOperation(floatingPoint, divident, divisor), nil
-	case '_':
-		argument1 = this.resultStack.Pop().(operation)
-		return newUnaryMinusOperation(argument1.OperationMetadata().DataType, argument1), nil
+		moduloOperation := newModuloOperation(floatingPoint, argument1, argument2)
+		moduloOperation.Position = operationToken.StartPosition
+		moduloOperation.Length = operationToken.Length
+		return moduloOperation, nil
 	case '^':
-		exponent := this.resultStack.Pop().(operation)
-		base := this.resultStack.Pop().(operation)
-		return newExponentiationOperation(floatingPoint, base, exponent), nil
+		exponentiationOperation := newExponentiationOperation(floatingPoint, argument1, argument2)
+		exponentiationOperation.Position = operationToken.StartPosition
+		exponentiationOperation.Length = operationToken.Length
+		return exponentiationOperation, nil
+	case '∧':
+		andOperation := newAndOperation(dataType, argument1, argument2)
+		andOperation.Position = operationToken.StartPosition
+		andOperation.Length = operationToken.Length
+		return andOperation, nil
+	case '∨':
+		orOperation := newOrOperation(dataType, argument1, argument2)
+		orOperation.Position = operationToken.StartPosition
+		orOperation.Length = operationToken.Length
+		return orOperation, nil
 	case '&':
-		argument2 = this.resultStack.Pop().(operation)
-		argument1 = this.resultStack.Pop().(operation)
-		dataType = requiredDataType(argument1, argument2)
-		return newAndOperation(dataType, argument1, argument2), nil
+		bitwiseAndOperation := newBitwiseAndOperation(integer, argument1, argument2)
+		bitwiseAndOperation.Position = operationToken.StartPosition
+		bitwiseAndOperation.Length = operationToken.Length
+		return bitwiseAndOperation, nil
 	case '|':
-		argument2 = this.resultStack.Pop().(operation)
-		argument1 = this.resultStack.Pop().(operation)
-		dataType = requiredDataType(argument1, argument2)
-		return newOrOperation(dataType, argument1, argument2), nil
+		bitwiseOrOperation := newBitwiseOrOperation(integer, argument1, argument2)
+		bitwiseOrOperation.Position = operationToken.StartPosition
+		bitwiseOrOperation.Length = operationToken.Length
+		return bitwiseOrOperation, nil
+	case '⊕':
+		bitwiseXorOperation := newBitwiseXorOperation(integer, argument1, argument2)
+		bitwiseXorOperation.Position = operationToken.StartPosition
+		bitwiseXorOperation.Length = operationToken.Length
+		return bitwiseXorOperation, nil
+	case '≪':
+		leftShiftOperation := newLeftShiftOperation(integer, argument1, argument2)
+		leftShiftOperation.Position = operationToken.StartPosition
+		leftShiftOperation.Length = operationToken.Length
+		return leftShiftOperation, nil
+	case '≫':
+		rightShiftOperation := newRightShiftOperation(integer, argument1, argument2)
+		rightShiftOperation.Position = operationToken.StartPosition
+		rightShiftOperation.Length = operationToken.Length
+		return rightShiftOperation, nil
+	case '⫽':
+		integerDivisionOperation := newIntegerDivisionOperation(integer, argument1, argument2)
+		integerDivisionOperation.Position = operationToken.StartPosition
+		integerDivisionOperation.Length = operationToken.Length
+		return integerDivisionOperation, nil
 	case '<':
-		argument2 = this.resultStack.Pop().(operation)
-		argument1 = this.resultStack.Pop().(operation)
-		dataType = requiredDataType(argument1, argument2)
-		return newLessThanOperation(dataType, argument1, argument2), nil
+		lessThanOperation := newLessThanOperation(dataType, argument1, argument2)
+		lessThanOperation.Position = operationToken.StartPosition
+		lessThanOperation.Length = operationToken.Length
+		return lessThanOperation, nil
 	case '≤':
-		argument2 = this.resultStack.Pop().(operation)
-		argument1 = this.resultStack.Pop().(operation)
-		dataType = requiredDataType(argument1, argument2)
-		return newLessOrEqualThanOperation(dataType, argument1, argument2), nil
+		lessOrEqualThanOperation := newLessOrEqualThanOperation(dataType, argument1, argument2)
+		lessOrEqualThanOperation.Position = operationToken.StartPosition
+		lessOrEqualThanOperation.Length = operationToken.Length
+		return lessOrEqualThanOperation, nil
 	case '>':
-		argument2 = this.resultStack.Pop().(operation)
-		argument1 = this.resultStack.Pop().(operation)
-		dataType = requiredDataType(argument1, argument2)
-		return newGreaterThanOperation(dataType, argument1, argument2), nil
+		greaterThanOperation := newGreaterThanOperation(dataType, argument1, argument2)
+		greaterThanOperation.Position = operationToken.StartPosition
+		greaterThanOperation.Length = operationToken.Length
+		return greaterThanOperation, nil
 	case '≥':
-		argument2 = this.resultStack.Pop().(operation)
-		argument1 = this.resultStack.Pop().(operation)
-		dataType = requiredDataType(argument1, argument2)
-		return newGreaterOrEqualThanOperation(dataType, argument1, argument2), nil
+		greaterOrEqualThanOperation := newGreaterOrEqualThanOperation(dataType, argument1, argument2)
+		greaterOrEqualThanOperation.Position = operationToken.StartPosition
+		greaterOrEqualThanOperation.Length = operationToken.Length
+		return greaterOrEqualThanOperation, nil
 	case '=':
-		argument2 = this.resultStack.Pop().(operation)
-		argument1 = this.resultStack.Pop().(operation)
-		dataType = requiredDataType(argument1, argument2)
-		return newEqualOperation(dataType, argument1, argument2), nil
+		equalOperation := newEqualOperation(dataType, argument1, argument2)
+		equalOperation.Position = operationToken.StartPosition
+		equalOperation.Length = operationToken.Length
+		return equalOperation, nil
+	case '⧺':
+		concatOperation := newConcatOperation(argument1, argument2)
+		concatOperation.Position = operationToken.StartPosition
+		concatOperation.Length = operationToken.Length
+		return concatOperation, nil
 	case '≠':
-		argument2 = this.resultStack.Pop().(operation)
-		argument1 = this.resultStack.Pop().(operation)
-		dataType = requiredDataType(argument1, argument2)
-		return newNotEqualOperation(dataType, argument1, argument2), nil
+		notEqualOperation := newNotEqualOperation(dataType, argument1, argument2)
+		notEqualOperation.Position = operationToken.StartPosition
+		notEqualOperation.Length = operationToken.Length
+		return notEqualOperation, nil
 	default:
-		return nil, fmt.Errorf("unknown operation %s", operationToken.Value)
+		return nil, &SyntaxError{Code: ErrorCodeInvalidToken,
+			Message:  fmt.Sprintf("unknown operation '%s'", tokenText(operationToken)),
+			Token:    tokenText(operationToken),
+			Position: operationToken.StartPosition,
+			Length:   operationToken.Length}
 	}
 }
 
 func (this astBuilder) build(tokens []token) (operation, error) {
 
-	for _, tokenItem := range tokens {
+	if len(tokens) == 0 {
+		return nil, &SyntaxError{Code: ErrorCodeEmptyFormula, Message: "formula cannot be empty"}
+	}
+
+	if this.implicitMultiplication {
+		tokens = this.insertImplicitMultiplications(tokens)
+	}
+
+	checker := this.newSyntaxChecker(tokens)
+
+	for idx, tokenItem := range tokens {
 		val := tokenItem.Value
 
+		if err := checker.checkPosition(idx); err != nil {
+			return nil, err
+		}
+		if errs := checker.advance(idx); len(errs) > 0 {
+			return nil, errs[0]
+		}
+
 		switch tokenItem.Type {
 		case tt_INTEGER:
 			this.resultStack.Push(newConstantOperation(integer, val))
@@ -230,11 +403,26 @@ func (this astBuilder) build(tokens []token) (operation, error) {
 		case tt_FLOATING_POINT:
 			this.resultStack.Push(newConstantOperation(floatingPoint, val))
 			break
+		case tt_STRING:
+			this.resultStack.Push(newConstantOperation(text, val))
+			break
+		case tt_DATE:
+			this.resultStack.Push(newConstantOperation(dateTime, val))
+			break
+		case tt_UNIT:
+			operand, err := withUnit(this.resultStack.Pop().(operation), tokenItem)
+			if err != nil {
+				return nil, err
+			}
+			this.resultStack.Push(operand)
+			break
 		case tt_TEXT:
 			tokenText := tokenItem.Value.(string)
+
 			if _, found := this.functionRegistry.get(tokenText); found {
+				// the number of parameters is known once the right bracket is reached
 				this.operatorStack.Push(tokenItem)
-				this.parameterCount.Push(1)
+				this.parameterCount.Push(0)
 			} else {
 
 				if this.compiledConstantRegistry != nil {
@@ -252,26 +440,41 @@ func (this astBuilder) build(tokens []token) (operation, error) {
 					if !this.caseSensitive {
 						tokenText = strings.ToLower(tokenText)
 					}
-					this.resultStack.Push(newVariableOperation(tokenText))
+					variableOperation := newVariableOperation(tokenText)
+					variableOperation.Position = tokenItem.StartPosition
+					variableOperation.Length = tokenItem.Length
+					this.resultStack.Push(variableOperation)
 				}
 			}
 			break
 		case tt_LEFT_BRACKET:
+			if this.maxNestingDepth > 0 && len(checker.brackets) > this.maxNestingDepth {
+				return nil, &MaxNestingDepthError{Position: tokenItem.StartPosition, Limit: this.maxNestingDepth}
+			}
 			this.operatorStack.Push(tokenItem)
 			break
 		case tt_RIGHT_BRACKET:
-			this.popOperations(true, &tokenItem)
+			if err := this.popOperations(true, &tokenItem); err != nil {
+				return nil, err
+			}
+
+			if checker.closedBracket.isFunction {
+				this.parameterCount.Pop()
+				this.parameterCount.Push(checker.closedBracket.parameterCount)
+			}
 			break
 		case tt_ARGUMENT_SEPARATOR:
-			this.popOperations(false, &tokenItem)
-			this.parameterCount.Push(this.parameterCount.Pop().(int) + 1)
+			if err := this.popOperations(false, &tokenItem); err != nil {
+				return nil, err
+			}
 			break
 		case tt_OPERATION:
 			operation1Token := tokenItem
 			// operation1 := []rune(operation1Token.Value.(string))[0]
 			operation1 := rune(operation1Token.Value.(int32))
 
-			for this.operatorStack.Len() > 0 && (this.operatorStack.Peek().(token).Type == tt_OPERATION || this.operatorStack.Peek().(token).Type == tt_TEXT) {
+			// a prefix operator comes before its operand, so it cannot complete the operators before it (i.e. '2^-x')
+			for !isPrefixOperation(operation1Token) && this.operatorStack.Len() > 0 && (this.operatorStack.Peek().(token).Type == tt_OPERATION || this.operatorStack.Peek().(token).Type == tt_TEXT) {
 
 				var operation2Token token
 				operation2Token = this.operatorStack.Peek().(token)
@@ -287,19 +490,21 @@ func (this astBuilder) build(tokens []token) (operation, error) {
 
 					if (isLeftAssociativeOperation(operation1) && precedences[operation1] <= precedences[operation2]) || (precedences[operation1] < precedences[operation2]) {
 						this.operatorStack.Pop()
-						t, err := this.convertOperation(operation2Token)
-						if err == nil {
-							this.resultStack.Push(t)
+						t, err := this.convertNestedOperation(operation2Token)
+						if err != nil {
+							return nil, err
 						}
+						this.resultStack.Push(t)
 					} else {
 						break
 					}
 				} else {
 					this.operatorStack.Pop()
-					t, err := this.convertFunction(operation2Token)
-					if err == nil {
-						this.resultStack.Push(t)
+					t, err := this.convertNestedOperation(operation2Token)
+					if err != nil {
+						return nil, err
 					}
+					this.resultStack.Push(t)
 				}
 			}
 
@@ -308,7 +513,13 @@ func (this astBuilder) build(tokens []token) (operation, error) {
 		}
 	}
 
-	this.popOperations(false, nil)
+	if errs := checker.end(); len(errs) > 0 {
+		return nil, errs[0]
+	}
+
+	if err := this.popOperations(false, nil); err != nil {
+		return nil, err
+	}
 
 	err := this.verifyResult()
 
@@ -320,8 +531,292 @@ func (this astBuilder) build(tokens []token) (operation, error) {
 	}
 }
 
+/*
+	Verify the structure of the formula without building it, collecting every problem found. After a
+	problem, the tokens are skipped until the next argument separator, bracket or function call, where
+	the verification resumes.
+*/
+func (this astBuilder) validate(tokens []token) []error {
+	var errs []error
+
+	if this.implicitMultiplication {
+		tokens = this.insertImplicitMultiplications(tokens)
+	}
+
+	checker := this.newSyntaxChecker(tokens)
+	isRecovering := false
+
+	for idx, tokenItem := range tokens {
+
+		if tokenItem.Type == tt_INVALID {
+			// already reported while reading the formula
+			isRecovering = true
+			continue
+		}
+
+		if isRecovering {
+			isFunctionCall := tokenItem.Type == tt_TEXT && idx+1 < len(tokens) && tokens[idx+1].Type == tt_LEFT_BRACKET
+
+			switch {
+			case tokenItem.Type == tt_LEFT_BRACKET || isFunctionCall:
+				checker.expectOperand = true
+			case tokenItem.Type == tt_RIGHT_BRACKET || tokenItem.Type == tt_ARGUMENT_SEPARATOR:
+				checker.expectOperand = false
+			default:
+				continue
+			}
+			isRecovering = false
+		}
+
+		if err := checker.checkPosition(idx); err != nil {
+			errs = append(errs, err)
+			isRecovering = true
+			continue
+		}
+		errs = append(errs, checker.advance(idx)...)
+	}
+
+	if isRecovering {
+		// the operand missing at the end was skipped with the rest of the problem
+		checker.expectOperand = false
+	}
+
+	return append(errs, checker.end()...)
+}
+
+/*
+	Follows the structure of a formula token by token: whether an operand is expected and which
+	brackets are open. Both 'build' and 'validate' go through it, so they accept the same formulas.
+*/
+type syntaxChecker struct {
+	functionRegistry *functionRegistry
+	tokens           []token
+	expectOperand    bool
+	brackets         []bracket
+	// the bracket closed by the last right bracket
+	closedBracket bracket
+}
+
+type bracket struct {
+	leftBracket    token
+	function       *functionInfo
+	functionToken  token
+	isFunction     bool
+	parameterCount int
+}
+
+func (this astBuilder) newSyntaxChecker(tokens []token) *syntaxChecker {
+	return &syntaxChecker{functionRegistry: this.functionRegistry, tokens: tokens, expectOperand: true}
+}
+
+// Verifies that the token at [idx] can appear after the tokens before it.
+func (this *syntaxChecker) checkPosition(idx int) error {
+	tokenItem := this.tokens[idx]
+
+	// right brackets are verified by 'advance', as they can close the arguments of a function call
+	if tokenItem.Type != tt_RIGHT_BRACKET && this.expectOperand != isOperandPosition(tokenItem) {
+		if this.expectOperand && idx > 0 {
+			return newMissingOperandError(this.tokens[idx-1])
+		}
+		return newUnexpectedTokenError(tokenItem)
+	}
+	return nil
+}
+
+// Moves past the token at [idx], returning the problems found in it (i.e. an unknown function).
+func (this *syntaxChecker) advance(idx int) []error {
+	var errs []error
+	tokens := this.tokens
+	tokenItem := tokens[idx]
+
+	switch tokenItem.Type {
+	case tt_INTEGER, tt_FLOATING_POINT, tt_STRING, tt_DATE:
+		this.expectOperand = false
+	case tt_TEXT:
+		isFunctionCall := idx+1 < len(tokens) && tokens[idx+1].Type == tt_LEFT_BRACKET
+
+		if _, found := this.functionRegistry.get(tokenItem.Value.(string)); found {
+			if !isFunctionCall {
+				errs = append(errs, &SyntaxError{Code: ErrorCodeMissingLeftBracket,
+					Message:  fmt.Sprintf("expected '(' after the function '%s'", tokenText(tokenItem)),
+					Token:    tokenText(tokenItem),
+					Position: tokenItem.StartPosition,
+					Length:   tokenItem.Length})
+				// verifies the rest as if it was a variable
+				this.expectOperand = false
+			}
+		} else {
+			if isFunctionCall {
+				errs = append(errs, newUnknownFunctionError(tokenItem))
+			} else {
+				this.expectOperand = false
+			}
+		}
+	case tt_LEFT_BRACKET:
+		item := bracket{leftBracket: tokenItem, parameterCount: 1}
+		if idx > 0 && tokens[idx-1].Type == tt_TEXT {
+			item.isFunction = true
+			item.functionToken = tokens[idx-1]
+			item.function, _ = this.functionRegistry.get(tokens[idx-1].Value.(string))
+		}
+		this.brackets = append(this.brackets, item)
+		this.expectOperand = true
+	case tt_RIGHT_BRACKET:
+		if len(this.brackets) == 0 {
+			errs = append(errs, &SyntaxError{Code: ErrorCodeMissingLeftBracket,
+				Message:  "no matching left bracket found for the right bracket",
+				Token:    tokenText(tokenItem),
+				Position: tokenItem.StartPosition,
+				Length:   tokenItem.Length})
+			this.expectOperand = false
+			break
+		}
+
+		item := this.brackets[len(this.brackets)-1]
+		this.brackets = this.brackets[:len(this.brackets)-1]
+
+		if this.expectOperand {
+			// a function can be called without arguments (i.e. 'foo()')
+			if item.isFunction && tokens[idx-1].Type == tt_LEFT_BRACKET {
+				item.parameterCount = 0
+			} else {
+				errs = append(errs, newMissingOperandError(tokens[idx-1]))
+			}
+		}
+
+		if item.function != nil {
+			if err := item.function.validateNumberOfParameters(item.parameterCount); err != nil {
+				errs = append(errs, &FunctionError{Code: ErrorCodeInvalidArguments,
+					Name:     item.functionToken.Value.(string),
+					Position: item.functionToken.StartPosition,
+					Length:   item.functionToken.Length,
+					Err:      err})
+			}
+		}
+		this.closedBracket = item
+		this.expectOperand = false
+	case tt_ARGUMENT_SEPARATOR:
+		if len(this.brackets) == 0 || !this.brackets[len(this.brackets)-1].isFunction {
+			errs = append(errs, newUnexpectedTokenError(tokenItem))
+		} else {
+			this.brackets[len(this.brackets)-1].parameterCount++
+		}
+		this.expectOperand = true
+	case tt_OPERATION:
+		this.expectOperand = !isPostfixOperation(tokenItem)
+	}
+
+	return errs
+}
+
+// Returns the problems found at the end of the formula: a missing operand and the brackets left open, innermost first.
+func (this *syntaxChecker) end() []error {
+	var errs []error
+
+	if this.expectOperand && len(this.tokens) > 0 {
+		errs = append(errs, newMissingOperandError(this.tokens[len(this.tokens)-1]))
+	}
+
+	for idx := len(this.brackets) - 1; idx >= 0; idx-- {
+		leftBracket := this.brackets[idx].leftBracket
+		errs = append(errs, &SyntaxError{Code: ErrorCodeMissingRightBracket,
+			Message:  "no matching right bracket found for the left bracket",
+			Token:    tokenText(leftBracket),
+			Position: leftBracket.StartPosition,
+			Length:   leftBracket.Length})
+	}
+
+	return errs
+}
+
+/*
+	Insert a multiplication between a number, a variable, a unit or a right bracket and the variable,
+	the function or the left bracket that follows it (i.e. '2x', '2pi r', '3(x+1)' or '(a)(b)'). A
+	name followed by a left bracket is a function call when the function is registered, otherwise it
+	is a variable multiplied by the brackets. A number is never the right operand of an implicit
+	multiplication, so 'x 2' is still an error. The multiplication has the precedence of '*'.
+*/
+func (this astBuilder) insertImplicitMultiplications(tokens []token) []token {
+	ret := make([]token, 0, len(tokens))
+
+	for idx, tokenItem := range tokens {
+		if idx > 0 && this.endsOperand(tokens[idx-1], tokenItem) && (tokenItem.Type == tt_TEXT || tokenItem.Type == tt_LEFT_BRACKET) {
+			ret = append(ret, token{Type: tt_OPERATION,
+				Value:         '*',
+				StartPosition: tokenItem.StartPosition,
+				Length:        0})
+		}
+		ret = append(ret, tokenItem)
+	}
+
+	return ret
+}
+
+// Tells whether the token [t], followed by [next], ends an operand.
+func (this astBuilder) endsOperand(t token, next token) bool {
+	switch t.Type {
+	case tt_INTEGER, tt_FLOATING_POINT, tt_UNIT, tt_RIGHT_BRACKET:
+		return true
+	case tt_OPERATION:
+		return isPostfixOperation(t)
+	case tt_TEXT:
+		_, isFunction := this.functionRegistry.get(t.Value.(string))
+		return !isFunction || next.Type != tt_LEFT_BRACKET
+	}
+	return false
+}
+
+// Tells whether the token can only appear where an operand is expected.
+func isOperandPosition(t token) bool {
+	switch t.Type {
+	case tt_INTEGER, tt_FLOATING_POINT, tt_STRING, tt_DATE, tt_TEXT, tt_LEFT_BRACKET:
+		return true
+	case tt_OPERATION:
+		return isPrefixOperation(t)
+	}
+	return false
+}
+
+// Tells whether the token is an operator written before its operand: the unary minus or the bitwise not.
+func isPrefixOperation(t token) bool {
+	return t.Type == tt_OPERATION && (t.Value == '_' || t.Value == '~')
+}
+
+// Tells whether the token is an operator written after its operand: the factorial or the percentage.
+func isPostfixOperation(t token) bool {
+	return t.Type == tt_OPERATION && (t.Value == '!' || t.Value == '％')
+}
+
+func newMissingOperandError(t token) error {
+	return &SyntaxError{Code: ErrorCodeMissingOperand,
+		Message:  fmt.Sprintf("missing operand after '%s'", tokenText(t)),
+		Token:    tokenText(t),
+		Position: t.StartPosition,
+		Length:   t.Length}
+}
+
+func newUnexpectedTokenError(t token) error {
+	return &SyntaxError{Code: ErrorCodeUnexpectedToken,
+		Message:  fmt.Sprintf("unexpected token '%s'", tokenText(t)),
+		Token:    tokenText(t),
+		Position: t.StartPosition,
+		Length:   t.Length}
+}
+
+func newUnknownFunctionError(t token) error {
+	return &SyntaxError{Code: ErrorCodeUnknownFunction,
+		Message:  fmt.Sprintf("unknown function '%s'", tokenText(t)),
+		Token:    tokenText(t),
+		Position: t.StartPosition,
+		Length:   t.Length}
+}
+
 func isLeftAssociativeOperation(character rune) bool {
-	return character == '*' || character == '+' || character == '-' || character == '/'
+	switch character {
+	case '*', '+', '-', '/', '!', '％', '&', '|', '⊕', '≪', '≫', '⫽':
+		return true
+	}
+	return false
 }
 
 func requiredDataType(argument1 operation, argument2 operation) operationDataType {
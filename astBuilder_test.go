@@ -1,6 +1,7 @@
 package gojacego
 
 import (
+	"errors"
 	"reflect"
 	"testing"
 )
@@ -129,3 +130,140 @@ func TestUnaryMinus(test *testing.T) {
 	}
 
 }
+
+func TestBuildSyntaxErrors(test *testing.T) {
+	functionRegistry := getFunctionRegistry()
+	registryDefaultFunctions(functionRegistry, &jaceOptions{})
+
+	scenarios := []struct {
+		formula  string
+		code     ErrorCode
+		position int
+		length   int
+	}{
+		{formula: "(1+2", code: ErrorCodeMissingRightBracket, position: 0, length: 1},
+		{formula: "1+2)", code: ErrorCodeMissingLeftBracket, position: 3, length: 1},
+		{formula: "1+", code: ErrorCodeMissingOperand, position: 1, length: 1},
+		{formula: "1 <= ", code: ErrorCodeMissingOperand, position: 2, length: 2},
+		{formula: "1 + * 2", code: ErrorCodeMissingOperand, position: 2, length: 1},
+		{formula: "*2", code: ErrorCodeUnexpectedToken, position: 0, length: 1},
+		{formula: "1 2", code: ErrorCodeUnexpectedToken, position: 2, length: 1},
+		{formula: "(1)(2)", code: ErrorCodeUnexpectedToken, position: 3, length: 1},
+		{formula: "1,2", code: ErrorCodeUnexpectedToken, position: 1, length: 1},
+		{formula: "max(1,(2,3))", code: ErrorCodeUnexpectedToken, position: 8, length: 1},
+		{formula: "max(1,)", code: ErrorCodeMissingOperand, position: 5, length: 1},
+		{formula: "()", code: ErrorCodeMissingOperand, position: 0, length: 1},
+		{formula: "foo(1)", code: ErrorCodeUnknownFunction, position: 0, length: 3},
+		{formula: "sin + 1", code: ErrorCodeMissingLeftBracket, position: 0, length: 3},
+	}
+
+	for _, scenario := range scenarios {
+		tokens, err := newTokenReader('.', ',').read(scenario.formula)
+		if err != nil {
+			test.Errorf("%s => unexpected error: %v", scenario.formula, err)
+			continue
+		}
+
+		astBuilder := newAstBuilder(false, functionRegistry, getConstantRegistry(), nil)
+		_, err = astBuilder.build(tokens)
+
+		var syntaxErr *SyntaxError
+		if !errors.As(err, &syntaxErr) {
+			test.Errorf("%s => expected: *SyntaxError, got: %v", scenario.formula, err)
+			continue
+		}
+
+		if syntaxErr.Code != scenario.code || syntaxErr.Position != scenario.position || syntaxErr.Length != scenario.length {
+			test.Errorf("%s => expected: %s at %d (%d), got: %s at %d (%d)", scenario.formula,
+				scenario.code, scenario.position, scenario.length, syntaxErr.Code, syntaxErr.Position, syntaxErr.Length)
+		}
+	}
+}
+
+func TestBuildFunctionArguments(test *testing.T) {
+	functionRegistry := getFunctionRegistry()
+	registryDefaultFunctions(functionRegistry, &jaceOptions{})
+
+	tokens, _ := newTokenReader('.', ',').read("1 + sin()")
+	_, err := newAstBuilder(false, functionRegistry, getConstantRegistry(), nil).build(tokens)
+
+	var fnErr *FunctionError
+	if !errors.As(err, &fnErr) || fnErr.Code != ErrorCodeInvalidArguments || fnErr.Position != 4 || fnErr.Length != 3 {
+		test.Errorf("expected: *FunctionError at 4, got: %v", err)
+	}
+
+	tokens, _ = newTokenReader('.', ',').read("max() + 1")
+	op, err := newAstBuilder(false, functionRegistry, getConstantRegistry(), nil).build(tokens)
+	if err != nil {
+		test.Errorf("unexpected error: %v", err)
+	}
+
+	addition := op.(*addOperation)
+	if len(addition.OperationOne.(*functionOperation).Arguments) != 0 {
+		test.Errorf("expected: no arguments")
+	}
+}
+
+func TestBuildIntegerFunction(test *testing.T) {
+	functionRegistry := getFunctionRegistry()
+	registryDefaultFunctions(functionRegistry, &jaceOptions{})
+	registryIntegerFunctions(functionRegistry, &jaceOptions{})
+
+	scenarios := []struct {
+		formula  string
+		expected operationDataType
+	}{
+		{formula: "gcd(12, 18)", expected: integer},
+		{formula: "sin(12)", expected: floatingPoint},
+	}
+
+	for _, scenario := range scenarios {
+		tokens, _ := newTokenReader('.', ',').read(scenario.formula)
+		op, err := newAstBuilder(false, functionRegistry, getConstantRegistry(), nil).build(tokens)
+		if err != nil {
+			test.Errorf("unexpected error: %v", err)
+			continue
+		}
+
+		if dataType := op.(*functionOperation).Metadata.DataType; dataType != scenario.expected {
+			test.Errorf("%s => expected: %v, got: %v", scenario.formula, scenario.expected, dataType)
+		}
+	}
+}
+
+func TestBuildImplicitMultiplication(test *testing.T) {
+	functionRegistry := getFunctionRegistry()
+	registryDefaultFunctions(functionRegistry, &jaceOptions{})
+
+	builder := newAstBuilder(false, functionRegistry, getConstantRegistry(), nil)
+	builder.implicitMultiplication = true
+
+	tokens, _ := newTokenReader('.', ',').read("2x(y)sin(z)")
+	tokens = builder.insertImplicitMultiplications(tokens)
+
+	operators := make([]token, 0)
+	for _, item := range tokens {
+		if item.Type == tt_OPERATION {
+			operators = append(operators, item)
+		}
+	}
+
+	if len(operators) != 3 {
+		test.Fatalf("expected: 3 multiplications, got: %v", tokens)
+	}
+
+	for i, position := range []int{1, 2, 5} {
+		if operators[i].Value != '*' || operators[i].StartPosition != position || operators[i].Length != 0 {
+			test.Errorf("expected: '*' at %d, got: %v", position, operators[i])
+		}
+	}
+
+	op, err := builder.build(tokens)
+	if err != nil {
+		test.Fatalf("unexpected error: %v", err)
+	}
+
+	if _, ok := op.(*multiplicationOperation); !ok {
+		test.Errorf("expected: a multiplication, got: %T", op)
+	}
+}
@@ -1,8 +1,12 @@
 package gojacego
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"math"
+	"math/rand"
+	"reflect"
 	"sort"
 	"strings"
 
@@ -10,12 +14,28 @@ import (
 )
 
 type jaceOptions struct {
-	decimalSeparator  *rune
-	argumentSeparator *rune
-	caseSensitive     *bool
-	optimizeEnabled   *bool
-	defaultConstants  *bool
-	defaultFunctions  *bool
+	decimalSeparator       *rune
+	argumentSeparator      *rune
+	caseSensitive          *bool
+	optimizeEnabled        *bool
+	defaultConstants       *bool
+	defaultFunctions       *bool
+	maxFormulaLength       int
+	maxNestingDepth        int
+	maxOperationDepth      int
+	maxTokens              int
+	maxEvaluationSteps     int
+	nonFinitePolicy        NonFinitePolicy
+	nonFiniteSubstitute    float64
+	missingVariables       missingVariables
+	converter              valueConverter
+	functionPacks          map[FunctionPack]bool
+	solver                 solverOptions
+	dialect                Dialect
+	random                 *randomGenerator
+	angleUnit              AngleUnit
+	units                  bool
+	implicitMultiplication bool
 }
 
 type JaceOptions interface {
@@ -113,6 +133,403 @@ func WithDefaultFunctions(enabled bool) JaceOptions {
 	}
 }
 
+/*
+	Limit the number of characters of a formula. Longer formulas are rejected with a *MaxFormulaLengthError.
+
+	Zero means no limit.
+*/
+func WithMaxFormulaLength(length int) JaceOptions {
+	return &applyOptions{
+		f: func(options *jaceOptions) error {
+			if length < 0 {
+				return errors.New("max formula length cannot be negative")
+			}
+			options.maxFormulaLength = length
+			return nil
+		},
+	}
+}
+
+/*
+	Limit how deep brackets can be nested in a formula (i.e. 'max(1,(2+3))' has a depth of 2).
+	Deeper formulas are rejected with a *MaxNestingDepthError.
+
+	Zero means no limit.
+*/
+func WithMaxNestingDepth(depth int) JaceOptions {
+	return &applyOptions{
+		f: func(options *jaceOptions) error {
+			if depth < 0 {
+				return errors.New("max nesting depth cannot be negative")
+			}
+			options.maxNestingDepth = depth
+			return nil
+		},
+	}
+}
+
+/*
+	Limit the depth of the operator tree built from a formula, counting every operator and function call
+	(i.e. '1+2+3' is built as '(1+2)+3' and has a depth of 2, like '-(-x)'). Deeper formulas, such as
+	long chains of additions, are rejected with a *MaxOperationDepthError.
+
+	Zero means no limit.
+*/
+func WithMaxOperationDepth(depth int) JaceOptions {
+	return &applyOptions{
+		f: func(options *jaceOptions) error {
+			if depth < 0 {
+				return errors.New("max operation depth cannot be negative")
+			}
+			options.maxOperationDepth = depth
+			return nil
+		},
+	}
+}
+
+/*
+	Limit the number of tokens (numbers, variables, operators, brackets...) of a formula.
+	Formulas with more tokens are rejected with a *MaxTokensError.
+
+	Zero means no limit.
+*/
+func WithMaxTokens(tokens int) JaceOptions {
+	return &applyOptions{
+		f: func(options *jaceOptions) error {
+			if tokens < 0 {
+				return errors.New("max tokens cannot be negative")
+			}
+			options.maxTokens = tokens
+			return nil
+		},
+	}
+}
+
+/*
+	Limit the number of operations executed by a single evaluation of a formula.
+	Evaluations that take more steps fail with a *MaxEvaluationStepsError.
+
+	Zero means no limit.
+*/
+func WithMaxEvaluationSteps(steps int) JaceOptions {
+	return &applyOptions{
+		f: func(options *jaceOptions) error {
+			if steps < 0 {
+				return errors.New("max evaluation steps cannot be negative")
+			}
+			options.maxEvaluationSteps = steps
+			return nil
+		},
+	}
+}
+
+/*
+	NonFinitePolicy tells what happens when an operation of a formula produces NaN or an infinity
+	(i.e. a division by zero or 'sqrt(-1)').
+*/
+type NonFinitePolicy int
+
+const (
+	// the IEEE values are returned as they are
+	PropagateNonFinite NonFinitePolicy = iota
+	// the evaluation fails with an *ArithmeticError naming the operation that produced the value
+	ErrorOnNonFinite
+	// the value is replaced by the one given to 'WithNonFiniteSubstitute', zero by default
+	SubstituteNonFinite
+)
+
+/*
+	Choose what happens when an operation produces NaN or an infinity. The default is PropagateNonFinite.
+*/
+func WithNonFinitePolicy(policy NonFinitePolicy) JaceOptions {
+	return &applyOptions{
+		f: func(options *jaceOptions) error {
+			if policy < PropagateNonFinite || policy > SubstituteNonFinite {
+				return fmt.Errorf("unknown non-finite policy %d", policy)
+			}
+			options.nonFinitePolicy = policy
+			return nil
+		},
+	}
+}
+
+/*
+	Replace by [value] the NaN and infinities produced by the operations of a formula.
+	It selects the SubstituteNonFinite policy.
+*/
+func WithNonFiniteSubstitute(value float64) JaceOptions {
+	return &applyOptions{
+		f: func(options *jaceOptions) error {
+			if math.IsNaN(value) || math.IsInf(value, 0) {
+				return errors.New("the substitute of non-finite values must be finite")
+			}
+			options.nonFinitePolicy = SubstituteNonFinite
+			options.nonFiniteSubstitute = value
+			return nil
+		},
+	}
+}
+
+/*
+	MissingVariablePolicy tells what value is used for a variable of a formula that is not given to the evaluation.
+*/
+type MissingVariablePolicy int
+
+const (
+	// the evaluation fails with an *UnknownVariableError
+	MissingVariableError MissingVariablePolicy = iota
+	// the variable is zero
+	MissingVariableZero
+	// the variable takes its value from the defaults given to 'WithVariableDefaults' or 'UsingVariableDefaults',
+	// the evaluation fails with an *UnknownVariableError when it has none
+	MissingVariableDefault
+	// the variable is NaN
+	MissingVariableNaN
+)
+
+/*
+	Choose the value used for the variables that are not given to the evaluation of a formula.
+	The default is MissingVariableError. It can be changed for a single evaluation with 'UsingMissingVariablePolicy'.
+*/
+func WithMissingVariablePolicy(policy MissingVariablePolicy) JaceOptions {
+	return &applyOptions{
+		f: func(options *jaceOptions) error {
+			if policy < MissingVariableError || policy > MissingVariableNaN {
+				return fmt.Errorf("unknown missing variable policy %d", policy)
+			}
+			options.missingVariables.policy = policy
+			return nil
+		},
+	}
+}
+
+/*
+	Use the values of [defaults] for the variables that are not given to the evaluation of a formula.
+	It selects the MissingVariableDefault policy.
+*/
+func WithVariableDefaults(defaults map[string]float64) JaceOptions {
+	return &applyOptions{
+		f: func(options *jaceOptions) error {
+			options.missingVariables = missingVariables{policy: MissingVariableDefault, defaults: defaults}
+			return nil
+		},
+	}
+}
+
+/*
+	Only convert the variables of the Go numeric types (int, float64, uint8...) when [enabled].
+	Otherwise, the default, booleans, numeral strings, 'json.Number', '*big.Float', '*big.Rat',
+	named numeric types and pointers to them are converted too. 'time.Time' and 'time.Duration'
+	are always converted to dates and durations.
+*/
+func WithStrictConversion(enabled bool) JaceOptions {
+	return &applyOptions{
+		f: func(options *jaceOptions) error {
+			options.converter.strict = enabled
+			return nil
+		},
+	}
+}
+
+/*
+	Convert the variables of the type [valueType] with [converter]. It takes precedence over the
+	built-in conversions and applies in strict mode too.
+*/
+func WithTypeConverter(valueType reflect.Type, converter TypeConverter) JaceOptions {
+	return &applyOptions{
+		f: func(options *jaceOptions) error {
+			if valueType == nil || converter == nil {
+				return errors.New("the type and the converter are required")
+			}
+			if options.converter.converters == nil {
+				options.converter.converters = map[reflect.Type]TypeConverter{}
+			}
+			options.converter.converters[valueType] = converter
+			return nil
+		},
+	}
+}
+
+/*
+	FunctionPack is a set of functions that is only registered when it is given to 'WithFunctionPack'.
+*/
+type FunctionPack int
+
+const (
+	// avg, median, mode, stdev, stdevp, var, varp, percentile, quantile, geomean, harmean, sumsq and correl
+	StatisticsPack FunctionPack = iota + 1
+	// pmt, ipmt, ppmt, fv, pv, nper, rate, npv, irr, xnpv, sln and db, compatible with Excel
+	FinancialPack
+	// gamma, lgamma, beta, erf, erfc, erfinv, j0, j1, jn, y0, y1, yn, expm1, log1p, hypot, atan2, cbrt and fact
+	SpecialMathPack
+	// fact, ncr, npr, gcd, lcm, isprime, fib, floordiv, powmod and digitsum, computed exactly on integers
+	IntegerPack
+)
+
+var functionPacks = map[FunctionPack]func(*functionRegistry, *jaceOptions){
+	StatisticsPack:  registryStatisticsFunctions,
+	FinancialPack:   registryFinancialFunctions,
+	SpecialMathPack: registrySpecialFunctions,
+	IntegerPack:     registryIntegerFunctions,
+}
+
+/*
+	Register the functions of the given [packs], in addition to the default functions.
+	A pack given more than once is registered once. With the SpecialMathPack and the IntegerPack,
+	'fact' is the exact factorial of the IntegerPack.
+*/
+func WithFunctionPack(packs ...FunctionPack) JaceOptions {
+	return &applyOptions{
+		f: func(options *jaceOptions) error {
+			for _, pack := range packs {
+				if _, found := functionPacks[pack]; !found {
+					return fmt.Errorf("unknown function pack %d", pack)
+				}
+				if options.functionPacks == nil {
+					options.functionPacks = map[FunctionPack]bool{}
+				}
+				options.functionPacks[pack] = true
+			}
+			return nil
+		},
+	}
+}
+
+/*
+	Stop the iterative functions ('rate' and 'irr' of the FinancialPack) once their result changes
+	by less than [tolerance] between two iterations. The default is 1e-10.
+*/
+func WithSolverTolerance(tolerance float64) JaceOptions {
+	return &applyOptions{
+		f: func(options *jaceOptions) error {
+			if !(tolerance > 0) || math.IsInf(tolerance, 0) {
+				return errors.New("the solver tolerance must be a positive number")
+			}
+			options.solver.tolerance = tolerance
+			return nil
+		},
+	}
+}
+
+/*
+	Fail the iterative functions ('rate' and 'irr' of the FinancialPack) with ErrNoConvergence when
+	they do not converge within [iterations]. The default is 100.
+*/
+func WithSolverMaxIterations(iterations int) JaceOptions {
+	return &applyOptions{
+		f: func(options *jaceOptions) error {
+			if iterations <= 0 {
+				return errors.New("the maximum number of solver iterations must be positive")
+			}
+			options.solver.maxIterations = iterations
+			return nil
+		},
+	}
+}
+
+/*
+	Draw the numbers of the random functions ('random', 'randint', 'randn' and 'choice') from [source],
+	i.e. 'rand.NewSource(42)' to make them reproducible. The source is used by this engine only and is
+	safe for concurrent evaluations. By default, a source seeded with the current time is used.
+	A context given by 'ContextWithRandomSeed' takes precedence for its evaluations.
+*/
+func WithRandomSource(source rand.Source) JaceOptions {
+	return &applyOptions{
+		f: func(options *jaceOptions) error {
+			if source == nil {
+				return errors.New("the random source cannot be nil")
+			}
+			options.random = newRandomGenerator(source)
+			return nil
+		},
+	}
+}
+
+/*
+	Choose the unit of the angles given to and returned by the trigonometric functions ('sin', 'cos',
+	'tan', their inverses, 'cot', 'sec', 'csc' and theirs). The literals in degrees (i.e. '30°') are
+	converted to this unit. The default is Radians.
+*/
+func WithAngleUnit(unit AngleUnit) JaceOptions {
+	return &applyOptions{
+		f: func(options *jaceOptions) error {
+			if unit < Radians || unit > Gradians {
+				return fmt.Errorf("unknown angle unit %d", unit)
+			}
+			options.angleUnit = unit
+			return nil
+		},
+	}
+}
+
+/*
+	Enable the physical units: the numbers can be followed by a unit (i.e. '5 km' or '9.81 m/s^2',
+	without spaces in the unit) and the numbers and the variables by a unit within brackets (i.e.
+	'speed[m/s]'). The units are multiplied, divided and raised to a power with the numbers, the
+	quantities added, subtracted and compared are converted to the unit of the left operand, and
+	mixing dimensions (i.e. 'm + s') is a TypeError, reported when the formula is built if possible.
+	The 'convert(x, "km/h")' function is registered.
+*/
+func WithUnits(enabled bool) JaceOptions {
+	return &applyOptions{
+		f: func(options *jaceOptions) error {
+			options.units = enabled
+			return nil
+		},
+	}
+}
+
+/*
+	Enable the implicit multiplication: a number, a variable or a right bracket followed by a variable,
+	a function or a left bracket is multiplied by it (i.e. '2x', '2pi r', '3(x+1)' or '(a)(b)'). A
+	name followed by '(' is a function call when the function is registered, otherwise the variable is
+	multiplied by the brackets. The multiplication has the precedence of '*', so '1/2x' is '(1/2)*x'.
+*/
+func WithImplicitMultiplication(enabled bool) JaceOptions {
+	return &applyOptions{
+		f: func(options *jaceOptions) error {
+			options.implicitMultiplication = enabled
+			return nil
+		},
+	}
+}
+
+/*
+	Dialect is the grammar of the formulas and the set of functions that comes with it.
+*/
+type Dialect int
+
+const (
+	DefaultDialect Dialect = iota
+	/*
+		The formulas of the spreadsheets: a leading '=', ';' as argument separator (in addition to the
+		configured one), '<>' and '=' for not equal and equal, '&' to concatenate, TRUE and FALSE, and
+		the functions ROUNDUP, ROUNDDOWN, MOD, POWER, AND, OR, NOT and IFERROR.
+	*/
+	Excel
+	/*
+		The formulas of the pocket calculators: a postfix '!' for the factorial (i.e. '5!') and a
+		postfix '%' for the percentage (i.e. 'price * 15%'). A '%' followed by an operand is still the
+		modulo, and '!=' is still the not equal operator.
+	*/
+	Calculator
+)
+
+/*
+	Choose the grammar of the formulas. The default is DefaultDialect.
+*/
+func WithDialect(dialect Dialect) JaceOptions {
+	return &applyOptions{
+		f: func(options *jaceOptions) error {
+			if dialect < DefaultDialect || dialect > Calculator {
+				return fmt.Errorf("unknown dialect %d", dialect)
+			}
+			options.dialect = dialect
+			return nil
+		},
+	}
+}
+
 /*
 	CalculationEngine represents the context of your evaluation engine.
 */
@@ -165,6 +582,14 @@ func buildOptions(options []JaceOptions) (*jaceOptions, error) {
 		opts.defaultFunctions = &defaultConstantsDefault
 	}
 
+	if opts.solver.tolerance == 0 {
+		opts.solver.tolerance = defaultSolverTolerance
+	}
+
+	if opts.solver.maxIterations == 0 {
+		opts.solver.maxIterations = defaultSolverMaxIterations
+	}
+
 	return &opts, nil
 }
 
@@ -179,7 +604,11 @@ func NewCalculationEngine(options ...JaceOptions) (*CalculationEngine, error) {
 		return nil, err
 	}
 
-	interpreter := &interpreter{}
+	interpreter := &interpreter{maxEvaluationSteps: opts.maxEvaluationSteps,
+		nonFinitePolicy:     opts.nonFinitePolicy,
+		nonFiniteSubstitute: opts.nonFiniteSubstitute,
+		missingVariables:    opts.missingVariables,
+		converter:           opts.converter}
 	optimizer := &optimizer{executor: *interpreter}
 	constantRegistry := newConstantRegistry(*opts.caseSensitive)
 	functionRegistry := newFunctionRegistry(*opts.caseSensitive)
@@ -189,7 +618,23 @@ func NewCalculationEngine(options ...JaceOptions) (*CalculationEngine, error) {
 	}
 
 	if *opts.defaultFunctions {
-		registryDefaultFunctions(functionRegistry)
+		registryDefaultFunctions(functionRegistry, opts)
+	}
+
+	if opts.units {
+		registryUnitFunctions(functionRegistry)
+	}
+
+	if opts.dialect == Excel {
+		registryExcelConstants(constantRegistry)
+		registryExcelFunctions(functionRegistry)
+	}
+
+	// in their order, so the functions registered by several packs (i.e. 'fact') are the ones of the last pack
+	for pack := StatisticsPack; pack <= IntegerPack; pack++ {
+		if opts.functionPacks[pack] {
+			functionPacks[pack](functionRegistry, opts)
+		}
 	}
 
 	return &CalculationEngine{
@@ -207,30 +652,91 @@ func NewCalculationEngine(options ...JaceOptions) (*CalculationEngine, error) {
 	Returns an error if the given expression has invalid syntax.
 */
 func (this *CalculationEngine) Calculate(formulaText string, vars map[string]interface{}) (float64, error) {
+	return this.CalculateContext(context.Background(), formulaText, vars)
+}
+
+/*
+	Parse and calculate from the given [formulaText] string using the given variables [vars] and
+	the context [ctx], which is passed on to the context-aware functions.
+	Returns an error if the given expression has invalid syntax or if the context is cancelled.
+*/
+func (this *CalculationEngine) CalculateContext(ctx context.Context, formulaText string, vars map[string]interface{}) (float64, error) {
+
+	evaluator, err := this.BuildEvaluator(formulaText)
+	if err != nil {
+		return 0, err
+	}
+
+	return evaluator.EvalContext(ctx, vars)
+}
+
+/*
+	Parse and calculate from the given [formulaText] string using the given variables [vars], returning
+	the result as a Value, which can be null.
+*/
+func (this *CalculationEngine) CalculateValue(formulaText string, vars map[string]interface{}) (Value, error) {
+
+	evaluator, err := this.BuildEvaluator(formulaText)
+	if err != nil {
+		return nullValue, err
+	}
+
+	return evaluator.EvalValue(vars)
+}
+
+/*
+	Parse the expression from the given [formulaText] string and build an Evaluator, which can be
+	evaluated with a context or with options for a single evaluation.
+	Returns an error if the given expression has invalid syntax.
+*/
+func (this *CalculationEngine) BuildEvaluator(formulaText string) (*Evaluator, error) {
+	return this.buildCachedEvaluator(formulaText, nil)
+}
+
+/*
+	Parse the expression from the given [formulaText] string and build an Evaluator with the given constants.
+	Returns an error if the given expression has invalid syntax.
+*/
+func (this *CalculationEngine) BuildEvaluatorWithConstants(formulaText string, vars map[string]interface{}) (*Evaluator, error) {
+
+	compiledConstantsRegistry := newConstantRegistry(*this.options.caseSensitive)
+
+	for k, p := range vars {
+		value, err := this.options.converter.toValue(p)
+		if err != nil || value.IsNull() {
+			return nil, fmt.Errorf("the variable '%s' cannot be converted to float", k)
+		}
+		compiledConstantsRegistry.registerConstant(k, value.number, true)
+	}
+
+	return this.buildCachedEvaluator(formulaText, compiledConstantsRegistry)
+}
+
+// Returns the evaluator from the cache, building and caching it when it is not there yet.
+func (this *CalculationEngine) buildCachedEvaluator(formulaText string, compiledConstantsRegistry *constantRegistry) (*Evaluator, error) {
 
 	if len(strings.TrimSpace(formulaText)) == 0 {
-		return 0, errors.New("the parameter 'formula' is required")
+		return nil, &SyntaxError{Code: ErrorCodeEmptyFormula, Message: "the parameter 'formula' is required"}
 	}
 
-	key := this.generateFormulaCacheKey(formulaText, nil)
+	key := this.generateFormulaCacheKey(formulaText, compiledConstantsRegistry)
 
 	item, found := this.cache.Get(key)
 
 	if found {
-		formula := item.(Formula)
-		return formula(vars)
+		return item.(*Evaluator), nil
 	}
 
-	op, err := this.buildAbstractSyntaxTree(formulaText, nil)
+	op, err := this.buildAbstractSyntaxTree(formulaText, compiledConstantsRegistry)
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
 
-	formula := this.buildFormula(formulaText, nil, op)
+	evaluator := this.buildEvaluator(formulaText, compiledConstantsRegistry, op)
 
-	this.cache.Add(key, formula)
+	this.cache.Add(key, evaluator)
 
-	return formula(vars)
+	return evaluator, nil
 }
 
 func (this *CalculationEngine) generateFormulaCacheKey(formulaText string, compiledConstantsRegistry *constantRegistry) string {
@@ -259,13 +765,13 @@ func (this *CalculationEngine) getFormula(formulaText string) Formula {
 
 	item, found := this.cache.Get(formulaText)
 	if found {
-		return item.(Formula)
+		return item.(*Evaluator).Formula()
 	}
 	return nil
 }
 
-func (this *CalculationEngine) buildFormula(formulaText string, compiledConstants *constantRegistry, operation operation) Formula {
-	return this.executor.buildFormula(operation, this.functionRegistry, this.constantRegistry)
+func (this *CalculationEngine) buildEvaluator(formulaText string, compiledConstants *constantRegistry, operation operation) *Evaluator {
+	return this.executor.buildEvaluator(operation, this.functionRegistry, this.constantRegistry)
 }
 
 /*
@@ -282,46 +788,20 @@ func (this *CalculationEngine) Build(formulaText string) (Formula, error) {
 */
 func (this *CalculationEngine) BuildWithConstants(formulaText string, vars map[string]interface{}) (Formula, error) {
 
-	if len(strings.TrimSpace(formulaText)) == 0 {
-		return nil, errors.New("the parameter 'formula' is required")
-	}
-
-	compiledConstantsRegistry := newConstantRegistry(*this.options.caseSensitive)
-
-	for k, p := range vars {
-		retFloat, err := toFloat64(p)
-		if err != nil {
-			return nil, fmt.Errorf("the variable '%s' cannot be converted to float", k)
-		}
-		compiledConstantsRegistry.registerConstant(k, retFloat, true)
-	}
-
-	key := this.generateFormulaCacheKey(formulaText, compiledConstantsRegistry)
-
-	item, found := this.cache.Get(key)
-
-	if found {
-		return item.(Formula), nil
-	}
-
-	op, err := this.buildAbstractSyntaxTree(formulaText, compiledConstantsRegistry)
+	evaluator, err := this.BuildEvaluatorWithConstants(formulaText, vars)
 	if err != nil {
 		return nil, err
 	}
 
-	formula := this.buildFormula(formulaText, compiledConstantsRegistry, op)
-
-	this.cache.Add(key, formula)
-
-	return formula, nil
+	return evaluator.Formula(), nil
 }
 
 /*
 	Add a custom constant to the calculation engine.
 */
 func (this *CalculationEngine) AddConstant(name string, value interface{}, isOverwritable bool) {
-	val, _ := toFloat64(value)
-	this.constantRegistry.registerConstant(name, val, isOverwritable)
+	val, _ := this.options.converter.toValue(value)
+	this.constantRegistry.registerConstant(name, val.number, isOverwritable)
 	this.cache.Invalidate()
 }
 
@@ -333,21 +813,69 @@ func (this *CalculationEngine) AddFunction(name string, body Delegate, isIdempot
 	this.cache.Invalidate()
 }
 
-func (this *CalculationEngine) buildAbstractSyntaxTree(formula string, compiledConstants *constantRegistry) (operation, error) {
+/*
+	Add a custom typed function to the calculation engine. The function receives its arguments as
+	float64 values and may return an error, which is reported by the formula as a *FunctionError.
+	It is called with [minParameters] to [maxParameters] arguments, a negative [maxParameters] meaning
+	no limit; the formulas with another number of arguments are rejected when they are built.
+*/
+func (this *CalculationEngine) AddTypedFunction(name string, body TypedDelegate, minParameters int, maxParameters int, isIdempotent bool) {
+	if maxParameters < 0 {
+		maxParameters = unlimitedParameters
+	}
+	this.functionRegistry.registerTypedFunction(name, body, minParameters, maxParameters, true, isIdempotent)
+	this.cache.Invalidate()
+}
 
-	tokenReader := newTokenReader(*this.options.decimalSeparator, *this.options.argumentSeparator)
-	astBuilder := newAstBuilder(*this.options.caseSensitive, this.functionRegistry, this.constantRegistry, compiledConstants)
+/*
+	Add a custom context-aware function to the calculation engine. The function receives the context
+	given to 'Formula.EvalContext' or 'CalculationEngine.CalculateContext'.
+*/
+func (this *CalculationEngine) AddContextFunction(name string, body ContextDelegate, isIdempotent bool) {
+	this.functionRegistry.registerContextFunction(name, body, 0, unlimitedParameters, true, isIdempotent)
+	this.cache.Invalidate()
+}
+
+/*
+	Add an arbitrary Go function to the calculation engine (i.e. 'func(x, lo, hi float64) float64').
+	The number of arguments is derived from the signature, which may be variadic. Parameters can be
+	numbers, bool or string, and the function must return a number or a bool, optionally followed by
+	an error. Functions added this way are never evaluated at build time by the optimizer.
+
+	Returns an error if the signature is not supported or if the function [name] exists and cannot be
+	overwritten (i.e. 'sin').
+*/
+func (this *CalculationEngine) AddGoFunc(name string, function interface{}) error {
+	if item, found := this.functionRegistry.get(name); found && !item.isOverWritable {
+		return fmt.Errorf("the function '%s' cannot be added: it cannot be overwritten", name)
+	}
+
+	goFunction, err := newGoFunction(function)
+	if err != nil {
+		return fmt.Errorf("the function '%s' cannot be added: %s", name, err.Error())
+	}
+
+	this.functionRegistry.registerValueFunction(name, withNullArguments(goFunction.call), goFunction.minParameters(), goFunction.maxParameters(), true, false)
+	this.cache.Invalidate()
+	return nil
+}
+
+func (this *CalculationEngine) buildAbstractSyntaxTree(formula string, compiledConstants *constantRegistry) (operation, error) {
 
-	tokens, err := tokenReader.read(formula)
+	tokens, err := this.newTokenReader().read(formula)
 	if err != nil {
 		return nil, err
 	}
 
-	operation, err := astBuilder.build(tokens)
+	operation, err := this.newAstBuilder(compiledConstants).build(tokens)
 	if err != nil {
 		return nil, err
 	}
 
+	if err := this.checkUnits(operation); err != nil {
+		return nil, err
+	}
+
 	if *this.options.optimizeEnabled {
 		optimizedOperation := this.optimizer.optimize(operation, this.functionRegistry, this.constantRegistry)
 		return optimizedOperation, nil
@@ -355,3 +883,31 @@ func (this *CalculationEngine) buildAbstractSyntaxTree(formula string, compiledC
 
 	return operation, nil
 }
+
+// Verifies the units of the formula when they are enabled.
+func (this *CalculationEngine) checkUnits(operation operation) error {
+	if !this.options.units {
+		return nil
+	}
+	_, err := checkUnits(operation)
+	return err
+}
+
+func (this *CalculationEngine) newTokenReader() *tokenReader {
+	tokenReader := newTokenReader(*this.options.decimalSeparator, *this.options.argumentSeparator)
+	tokenReader.maxFormulaLength = this.options.maxFormulaLength
+	tokenReader.maxTokens = this.options.maxTokens
+	tokenReader.dialect = this.options.dialect
+	tokenReader.angleUnit = this.options.angleUnit
+	tokenReader.units = this.options.units
+	tokenReader.caseSensitive = *this.options.caseSensitive
+	return tokenReader
+}
+
+func (this *CalculationEngine) newAstBuilder(compiledConstants *constantRegistry) *astBuilder {
+	astBuilder := newAstBuilder(*this.options.caseSensitive, this.functionRegistry, this.constantRegistry, compiledConstants)
+	astBuilder.maxNestingDepth = this.options.maxNestingDepth
+	astBuilder.maxOperationDepth = this.options.maxOperationDepth
+	astBuilder.implicitMultiplication = this.options.implicitMultiplication
+	return astBuilder
+}
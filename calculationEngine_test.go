@@ -1,8 +1,17 @@
 package gojacego
 
 import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"math"
+	"math/rand"
+	"reflect"
+	"strings"
 	"testing"
+	"time"
 )
 
 type CalculationTestScenario struct {
@@ -46,6 +55,13 @@ func getCalculationScenarios() []CalculationTestScenario {
 			formula:        "5*-100",
 			expectedResult: -500.0,
 		},
+		{
+			formula: "2^-var1",
+			variables: map[string]interface{}{
+				"var1": 2,
+			},
+			expectedResult: 0.25,
+		},
 		{
 			formula:        "-(1+2+(3+4))",
 			expectedResult: -10.0,
@@ -537,6 +553,12 @@ func TestCompiledConstants(test *testing.T) {
 	if result != 6 {
 		test.Errorf("expected: 6.0, got: %f", result)
 	}
+
+	evaluator, _ := engine.BuildEvaluatorWithConstants("a+b+c", constants)
+
+	if result, err := evaluator.EvalContext(context.Background(), input); err != nil || result != 6 {
+		test.Errorf("expected: 6.0, got: %v (%v)", result, err)
+	}
 }
 
 func TestCaseUnsensitive(test *testing.T) {
@@ -711,3 +733,1822 @@ func BenchmarkGenerateCacheKey(bench *testing.B) {
 		engine.generateFormulaCacheKey("a+b+c", registry)
 	}
 }
+
+func TestCustomTypedFunctions(test *testing.T) {
+	engine, _ := NewCalculationEngine()
+
+	engine.AddTypedFunction("sum", func(arguments []float64) (float64, error) {
+		return arguments[0] + arguments[1], nil
+	}, 2, 2, true)
+
+	result, err := engine.Calculate("sum(2.4,2.4)", nil)
+	if err != nil {
+		test.Errorf("unexpected error: %v", err)
+	}
+
+	if result != 4.8 {
+		test.Errorf("expected: 4.8, got: %f", result)
+	}
+
+	for _, formula := range []string{"sum(1)", "sum(1, 2, 3)"} {
+		_, err := engine.Calculate(formula, nil)
+
+		var fnErr *FunctionError
+		if !errors.As(err, &fnErr) || fnErr.Code != ErrorCodeInvalidArguments {
+			test.Errorf("%s => expected: *FunctionError with invalid arguments, got: %v", formula, err)
+		}
+	}
+
+	engine.AddTypedFunction("total", func(arguments []float64) (float64, error) {
+		return float64(len(arguments)), nil
+	}, 0, -1, true)
+
+	if result, err := engine.Calculate("total() + total(1, 2, 3)", nil); err != nil || result != 3 {
+		test.Errorf("expected: 3, got: %v (%v)", result, err)
+	}
+}
+
+func TestCustomTypedFunctionError(test *testing.T) {
+	engine, _ := NewCalculationEngine()
+
+	errNegative := errors.New("negative value")
+
+	engine.AddTypedFunction("positive", func(arguments []float64) (float64, error) {
+		if arguments[0] < 0 {
+			return 0, errNegative
+		}
+		return arguments[0], nil
+	}, 1, 1, true)
+
+	scenarios := []string{"1 + positive(-2)", "1 + positive(x)"}
+
+	for _, formula := range scenarios {
+		_, err := engine.Calculate(formula, map[string]interface{}{"x": -2})
+
+		var fnErr *FunctionError
+		if !errors.As(err, &fnErr) {
+			test.Errorf("%s => expected: *FunctionError, got: %v", formula, err)
+			continue
+		}
+
+		if fnErr.Name != "positive" || fnErr.Position != 4 {
+			test.Errorf("%s => expected: positive at 4, got: %s at %d", formula, fnErr.Name, fnErr.Position)
+		}
+
+		if !errors.Is(err, errNegative) {
+			test.Errorf("%s => expected: %v, got: %v", formula, errNegative, err)
+		}
+	}
+}
+
+func TestStandardFunctionsNumberOfArguments(test *testing.T) {
+	engine, _ := NewCalculationEngine()
+
+	scenarios := []string{"sin(1, 2)", "round(1, 2, 3)", "if(1, 2)"}
+
+	for _, formula := range scenarios {
+		_, err := engine.Calculate(formula, nil)
+
+		var fnErr *FunctionError
+		if !errors.As(err, &fnErr) {
+			test.Errorf("%s => expected: *FunctionError, got: %v", formula, err)
+		}
+	}
+}
+
+type rateKey struct{}
+
+func TestContextFunctions(test *testing.T) {
+	engine, _ := NewCalculationEngine()
+
+	engine.AddContextFunction("rate", func(ctx context.Context, arguments []float64) (float64, error) {
+		rate, ok := ctx.Value(rateKey{}).(float64)
+		if !ok {
+			return 0, errors.New("rate not found")
+		}
+		return arguments[0] * rate, nil
+	}, false)
+
+	ctx := context.WithValue(context.Background(), rateKey{}, 1.5)
+
+	result, err := engine.CalculateContext(ctx, "rate(10) + 1", nil)
+	if err != nil || result != 16 {
+		test.Errorf("expected: 16, got: %f (%v)", result, err)
+	}
+
+	evaluator, _ := engine.BuildEvaluator("rate(x)")
+
+	result, err = evaluator.EvalContext(ctx, map[string]interface{}{"x": 4})
+	if err != nil || result != 6 {
+		test.Errorf("expected: 6, got: %f (%v)", result, err)
+	}
+
+	_, err = evaluator.Eval(map[string]interface{}{"x": 4})
+	if err == nil {
+		test.Errorf("error should not be null")
+	}
+
+	formula, _ := engine.Build("rate(x)")
+
+	_, err = formula(map[string]interface{}{"x": 4})
+	if err == nil {
+		test.Errorf("error should not be null")
+	}
+}
+
+func TestContextCancellation(test *testing.T) {
+	engine, _ := NewCalculationEngine()
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	calls := 0
+	engine.AddContextFunction("step", func(ctx context.Context, arguments []float64) (float64, error) {
+		calls++
+		cancel()
+		return arguments[0], nil
+	}, false)
+
+	evaluator, _ := engine.BuildEvaluator("step(1) + step(2) + step(3)")
+
+	_, err := evaluator.EvalContext(ctx, nil)
+	if err != context.Canceled {
+		test.Errorf("expected: %v, got: %v", context.Canceled, err)
+	}
+
+	if calls != 1 {
+		test.Errorf("expected: 1 call, got: %d", calls)
+	}
+
+	_, err = engine.CalculateContext(ctx, "1 + 2", nil)
+	if err != context.Canceled {
+		test.Errorf("expected: %v, got: %v", context.Canceled, err)
+	}
+}
+
+func TestMaxFormulaLength(test *testing.T) {
+	engine, _ := NewCalculationEngine(WithMaxFormulaLength(5))
+
+	if _, err := engine.Calculate("1+2+3", nil); err != nil {
+		test.Errorf("unexpected error: %v", err)
+	}
+
+	_, err := engine.Calculate("1+2+34", nil)
+
+	var lengthErr *MaxFormulaLengthError
+	if !errors.As(err, &lengthErr) || lengthErr.Length != 6 || lengthErr.Limit != 5 {
+		test.Errorf("expected: *MaxFormulaLengthError, got: %v", err)
+	}
+}
+
+func TestMaxTokens(test *testing.T) {
+	engine, _ := NewCalculationEngine(WithMaxTokens(5))
+
+	if _, err := engine.Calculate("1 + 2 + 3", nil); err != nil {
+		test.Errorf("unexpected error: %v", err)
+	}
+
+	_, err := engine.Calculate("1 + 2 + 3 + 4", nil)
+
+	var tokensErr *MaxTokensError
+	if !errors.As(err, &tokensErr) || tokensErr.Position != 10 {
+		test.Errorf("expected: *MaxTokensError at 10, got: %v", err)
+	}
+}
+
+func TestMaxNestingDepth(test *testing.T) {
+	engine, _ := NewCalculationEngine(WithMaxNestingDepth(2))
+
+	if _, err := engine.Calculate("max(1, (2 + 3)) + (4)", nil); err != nil {
+		test.Errorf("unexpected error: %v", err)
+	}
+
+	_, err := engine.Calculate("max(1, ((2 + 3)))", nil)
+
+	var depthErr *MaxNestingDepthError
+	if !errors.As(err, &depthErr) || depthErr.Position != 8 {
+		test.Errorf("expected: *MaxNestingDepthError at 8, got: %v", err)
+	}
+
+	formula := strings.Repeat("(", 10000) + "1" + strings.Repeat(")", 10000)
+
+	_, err = engine.Calculate(formula, nil)
+	if !errors.As(err, &depthErr) {
+		test.Errorf("expected: *MaxNestingDepthError, got: %v", err)
+	}
+
+	// only the brackets are nested, not the operations
+	for _, formula := range []string{"1 + 2*3^4", "1+2+3+4+5", "--1", "-(-1)"} {
+		if _, err := engine.Calculate(formula, nil); err != nil {
+			test.Errorf("formula: %s, unexpected error: %v", formula, err)
+		}
+	}
+}
+
+func TestMaxOperationDepth(test *testing.T) {
+	engine, _ := NewCalculationEngine(WithMaxOperationDepth(2))
+
+	for _, formula := range []string{"1 + 2*3", "--1", "(1 + 2) + (3 + 4)", "max(1, 2 + 3)"} {
+		if _, err := engine.Calculate(formula, nil); err != nil {
+			test.Errorf("formula: %s, unexpected error: %v", formula, err)
+		}
+	}
+
+	_, err := engine.Calculate("1 + 2 + 3 + 4", nil)
+
+	var depthErr *MaxOperationDepthError
+	if !errors.As(err, &depthErr) || depthErr.Position != 10 {
+		test.Errorf("expected: *MaxOperationDepthError at 10, got: %v", err)
+	}
+
+	for _, formula := range []string{"-(-(-(1)))", "max(1, 2 + 3) * 2", strings.Repeat("1+", 200000) + "1", strings.Repeat("-", 200000) + "1"} {
+		if _, err := engine.Calculate(formula, nil); !errors.As(err, &depthErr) {
+			test.Errorf("formula: %.20s, expected: *MaxOperationDepthError, got: %v", formula, err)
+		}
+	}
+}
+
+func TestMaxEvaluationSteps(test *testing.T) {
+	engine, _ := NewCalculationEngine(WithMaxEvaluationSteps(5))
+
+	vars := map[string]interface{}{
+		"a": 1,
+		"b": 2,
+	}
+
+	if _, err := engine.Calculate("a + b * 2", vars); err != nil {
+		test.Errorf("unexpected error: %v", err)
+	}
+
+	_, err := engine.Calculate("a + b * 2 + a", vars)
+
+	var stepsErr *MaxEvaluationStepsError
+	if !errors.As(err, &stepsErr) || stepsErr.Limit != 5 {
+		test.Errorf("expected: *MaxEvaluationStepsError, got: %v", err)
+	}
+}
+
+func TestInvalidLimits(test *testing.T) {
+	options := []JaceOptions{
+		WithMaxFormulaLength(-1),
+		WithMaxNestingDepth(-1),
+		WithMaxTokens(-1),
+		WithMaxEvaluationSteps(-1),
+	}
+
+	for _, option := range options {
+		if _, err := NewCalculationEngine(option); err == nil {
+			test.Errorf("error should not be null")
+		}
+	}
+}
+
+func TestEvaluationErrors(test *testing.T) {
+	engine, _ := NewCalculationEngine()
+
+	_, err := engine.Calculate("a + bb", map[string]interface{}{"a": 1})
+
+	var variableErr *UnknownVariableError
+	if !errors.As(err, &variableErr) || variableErr.Name != "bb" || variableErr.Position != 4 || variableErr.Length != 2 {
+		test.Errorf("expected: *UnknownVariableError at 4, got: %v", err)
+	}
+
+	_, err = engine.Calculate("1 + a", map[string]interface{}{"a": "text"})
+
+	var typeErr *TypeError
+	if !errors.As(err, &typeErr) || typeErr.Code != ErrorCodeTypeMismatch || typeErr.Position != 4 {
+		test.Errorf("expected: *TypeError at 4, got: %v", err)
+	}
+
+	_, err = engine.Calculate("1 + 2 !", nil)
+
+	var syntaxErr *SyntaxError
+	if !errors.As(err, &syntaxErr) || syntaxErr.Code != ErrorCodeInvalidToken || syntaxErr.Position != 6 || syntaxErr.Token != "!" {
+		test.Errorf("expected: *SyntaxError at 6, got: %v", err)
+	}
+
+	_, err = engine.Calculate(" ", nil)
+	if !errors.As(err, &syntaxErr) || syntaxErr.Code != ErrorCodeEmptyFormula {
+		test.Errorf("expected: *SyntaxError, got: %v", err)
+	}
+}
+
+func TestNonFinitePropagate(test *testing.T) {
+	engine, _ := NewCalculationEngine()
+
+	result, err := engine.Calculate("1 / 0", nil)
+	if err != nil || !math.IsInf(result, 1) {
+		test.Errorf("expected: +Inf, got: %v (%v)", result, err)
+	}
+
+	result, err = engine.Calculate("sqrt(-1)", nil)
+	if err != nil || !math.IsNaN(result) {
+		test.Errorf("expected: NaN, got: %v (%v)", result, err)
+	}
+}
+
+func TestNonFiniteError(test *testing.T) {
+	for _, optimizeEnabled := range []bool{true, false} {
+		engine, _ := NewCalculationEngine(WithNonFinitePolicy(ErrorOnNonFinite), WithOptimizeEnabled(optimizeEnabled))
+
+		scenarios := []struct {
+			formula   string
+			operation string
+			position  int
+		}{
+			{formula: "1 + 1 / 0", operation: "division", position: 6},
+			{formula: "2 * sqrt(-1)", operation: "function 'sqrt'", position: 4},
+			{formula: "1 + log(a)", operation: "function 'log'", position: 4},
+			{formula: "a + b", operation: "variable 'b'", position: 4},
+			{formula: "10 ^ 400", operation: "exponentiation", position: 3},
+			{formula: "(a + 1) % 0", operation: "modulo", position: 8},
+		}
+
+		vars := map[string]interface{}{
+			"a": 0,
+			"b": math.Inf(-1),
+		}
+
+		for _, scenario := range scenarios {
+			_, err := engine.Calculate(scenario.formula, vars)
+
+			var arithmeticErr *ArithmeticError
+			if !errors.As(err, &arithmeticErr) {
+				test.Errorf("%s => expected: *ArithmeticError, got: %v", scenario.formula, err)
+				continue
+			}
+
+			if arithmeticErr.Code != ErrorCodeArithmetic || arithmeticErr.Operation != scenario.operation || arithmeticErr.Position != scenario.position {
+				test.Errorf("%s => expected: %s at %d, got: %s at %d", scenario.formula,
+					scenario.operation, scenario.position, arithmeticErr.Operation, arithmeticErr.Position)
+			}
+		}
+
+		if result, err := engine.Calculate("1 / 4", nil); err != nil || result != 0.25 {
+			test.Errorf("expected: 0.25, got: %v (%v)", result, err)
+		}
+	}
+}
+
+func TestNonFiniteSubstitute(test *testing.T) {
+	engine, _ := NewCalculationEngine(WithNonFiniteSubstitute(-1))
+
+	scenarios := []struct {
+		formula  string
+		expected float64
+	}{
+		{formula: "1 + 1 / 0", expected: 0},
+		{formula: "log(0) * 2", expected: -2},
+		{formula: "sqrt(-1)", expected: -1},
+		{formula: "a / a", expected: -1},
+	}
+
+	for _, scenario := range scenarios {
+		result, err := engine.Calculate(scenario.formula, map[string]interface{}{"a": 0})
+		if err != nil || result != scenario.expected {
+			test.Errorf("%s => expected: %v, got: %v (%v)", scenario.formula, scenario.expected, result, err)
+		}
+	}
+
+	engine, _ = NewCalculationEngine(WithNonFinitePolicy(SubstituteNonFinite))
+	if result, _ := engine.Calculate("1 / 0", nil); result != 0 {
+		test.Errorf("expected: 0, got: %v", result)
+	}
+}
+
+func TestInvalidNonFiniteOptions(test *testing.T) {
+	options := []JaceOptions{
+		WithNonFinitePolicy(NonFinitePolicy(10)),
+		WithNonFiniteSubstitute(math.NaN()),
+		WithNonFiniteSubstitute(math.Inf(1)),
+	}
+
+	for _, option := range options {
+		if _, err := NewCalculationEngine(option); err == nil {
+			test.Errorf("error should not be null")
+		}
+	}
+}
+
+func TestMissingVariablePolicies(test *testing.T) {
+	vars := map[string]interface{}{"a": 2}
+
+	engine, _ := NewCalculationEngine(WithMissingVariablePolicy(MissingVariableZero))
+	if result, err := engine.Calculate("a + b", vars); err != nil || result != 2 {
+		test.Errorf("expected: 2, got: %v (%v)", result, err)
+	}
+
+	engine, _ = NewCalculationEngine(WithMissingVariablePolicy(MissingVariableNaN))
+	if result, err := engine.Calculate("a + b", vars); err != nil || !math.IsNaN(result) {
+		test.Errorf("expected: NaN, got: %v (%v)", result, err)
+	}
+
+	engine, _ = NewCalculationEngine(WithVariableDefaults(map[string]float64{"b": 5}))
+	if result, err := engine.Calculate("a * b", vars); err != nil || result != 10 {
+		test.Errorf("expected: 10, got: %v (%v)", result, err)
+	}
+
+	_, err := engine.Calculate("a * c", vars)
+
+	var variableErr *UnknownVariableError
+	if !errors.As(err, &variableErr) || variableErr.Name != "c" {
+		test.Errorf("expected: *UnknownVariableError, got: %v", err)
+	}
+}
+
+func TestMissingVariablePerEvaluation(test *testing.T) {
+	vars := map[string]interface{}{"a": 2}
+
+	engine, _ := NewCalculationEngine()
+
+	evaluator, _ := engine.BuildEvaluator("a + b")
+	if result, err := evaluator.Eval(vars, UsingMissingVariablePolicy(MissingVariableZero)); err != nil || result != 2 {
+		test.Errorf("expected: 2, got: %v (%v)", result, err)
+	}
+
+	evaluator, _ = engine.BuildEvaluator("a - b")
+	if result, err := evaluator.Eval(vars, UsingVariableDefaults(map[string]float64{"b": 3})); err != nil || result != -1 {
+		test.Errorf("expected: -1, got: %v (%v)", result, err)
+	}
+
+	// the policy of the engine is used again once the evaluation is over
+	if _, err := evaluator.Eval(vars); err == nil {
+		test.Errorf("error should not be null")
+	}
+
+	engine, _ = NewCalculationEngine(WithMissingVariablePolicy(MissingVariableZero))
+	evaluator, _ = engine.BuildEvaluator("a + b")
+	if _, err := evaluator.Eval(vars, UsingMissingVariablePolicy(MissingVariableError)); err == nil {
+		test.Errorf("error should not be null")
+	}
+}
+
+func TestDefaultAndIsDefinedFunctions(test *testing.T) {
+	for _, optimizeEnabled := range []bool{true, false} {
+		engine, _ := NewCalculationEngine(WithOptimizeEnabled(optimizeEnabled))
+
+		scenarios := []struct {
+			formula  string
+			vars     map[string]interface{}
+			expected float64
+		}{
+			{formula: "default(x, 10)", vars: nil, expected: 10},
+			{formula: "default(x, 10)", vars: map[string]interface{}{"x": 3}, expected: 3},
+			{formula: "default(x, y * 2) + 1", vars: map[string]interface{}{"y": 4}, expected: 9},
+			{formula: "default(2 + 3, 10)", vars: nil, expected: 5},
+			{formula: "isdefined(x)", vars: nil, expected: 0},
+			{formula: "isdefined(x)", vars: map[string]interface{}{"x": 0}, expected: 1},
+			{formula: "if(isdefined(x), 1, -1)", vars: nil, expected: -1},
+		}
+
+		for _, scenario := range scenarios {
+			result, err := engine.Calculate(scenario.formula, scenario.vars)
+			if err != nil || result != scenario.expected {
+				test.Errorf("%s => expected: %v, got: %v (%v)", scenario.formula, scenario.expected, result, err)
+			}
+		}
+
+		_, err := engine.Calculate("default(x + 1, 10)", nil)
+
+		var variableErr *UnknownVariableError
+		if !errors.As(err, &variableErr) {
+			test.Errorf("expected: *UnknownVariableError, got: %v", err)
+		}
+	}
+}
+
+func TestDefaultAndIsDefinedCanBeOverwritten(test *testing.T) {
+	engine, _ := NewCalculationEngine()
+
+	for _, name := range []string{"isdefined", "default"} {
+		engine.AddFunction(name, func(arguments ...interface{}) float64 {
+			return 42
+		}, true)
+
+		if result, err := engine.Calculate(name+"(1, 2)", nil); err != nil || result != 42 {
+			test.Errorf("%s => expected: 42, got: %v (%v)", name, result, err)
+		}
+	}
+}
+
+func TestInvalidMissingVariablePolicy(test *testing.T) {
+	if _, err := NewCalculationEngine(WithMissingVariablePolicy(MissingVariablePolicy(10))); err == nil {
+		test.Errorf("error should not be null")
+	}
+}
+
+func TestNullPropagation(test *testing.T) {
+	vars := map[string]interface{}{
+		"a": 2,
+		"n": nil,
+		"t": 1,
+		"f": 0,
+	}
+
+	scenarios := []struct {
+		formula  string
+		expected Value
+	}{
+		{formula: "a + n", expected: NullValue()},
+		{formula: "-n * 2", expected: NullValue()},
+		{formula: "n ^ 0", expected: NullValue()},
+		{formula: "a < n", expected: NullValue()},
+		{formula: "n == n", expected: NullValue()},
+		{formula: "sin(n)", expected: NullValue()},
+		{formula: "max(1, n, 3)", expected: NullValue()},
+		{formula: "f && n", expected: NumberValue(0)},
+		{formula: "t && n", expected: NullValue()},
+		{formula: "t || n", expected: NumberValue(1)},
+		{formula: "f || n", expected: NullValue()},
+		{formula: "if(n > 1, 10, 20)", expected: NumberValue(20)},
+		{formula: "isnull(n) + isnull(a)", expected: NumberValue(1)},
+		{formula: "coalesce(n, n, a * 3)", expected: NumberValue(6)},
+		{formula: "coalesce(n)", expected: NullValue()},
+		{formula: "nullif(a, 2)", expected: NullValue()},
+		{formula: "nullif(a, 3)", expected: NumberValue(2)},
+		{formula: "nullif(1, 1)", expected: NullValue()},
+		{formula: "isdefined(n)", expected: NumberValue(1)},
+		{formula: "default(n, 5)", expected: NullValue()},
+	}
+
+	for _, optimizeEnabled := range []bool{true, false} {
+		engine, _ := NewCalculationEngine(WithOptimizeEnabled(optimizeEnabled))
+
+		for _, scenario := range scenarios {
+			result, err := engine.CalculateValue(scenario.formula, vars)
+			if err != nil || result != scenario.expected {
+				test.Errorf("%s => expected: %v, got: %v (%v)", scenario.formula, scenario.expected, result, err)
+			}
+		}
+	}
+}
+
+func TestNullResult(test *testing.T) {
+	engine, _ := NewCalculationEngine()
+
+	_, err := engine.Calculate("a + 1", map[string]interface{}{"a": sql.NullFloat64{}})
+	if !errors.Is(err, ErrNullResult) {
+		test.Errorf("expected: ErrNullResult, got: %v", err)
+	}
+
+	evaluator, _ := engine.BuildEvaluator("a + 1")
+
+	result, err := evaluator.EvalValue(map[string]interface{}{"a": sql.NullFloat64{Float64: 1, Valid: true}})
+	if err != nil || result != NumberValue(2) {
+		test.Errorf("expected: 2, got: %v (%v)", result, err)
+	}
+
+	result, err = evaluator.EvalValue(map[string]interface{}{"a": nil})
+	if err != nil || !result.IsNull() {
+		test.Errorf("expected: null, got: %v (%v)", result, err)
+	}
+
+	if _, err := evaluator.EvalValue(nil); err == nil {
+		test.Errorf("error should not be null")
+	}
+}
+
+func TestNullFunctionsCanBeOverwritten(test *testing.T) {
+	engine, _ := NewCalculationEngine()
+
+	for _, name := range []string{"isnull", "coalesce", "nullif"} {
+		engine.AddFunction(name, func(arguments ...interface{}) float64 {
+			return 42
+		}, true)
+
+		if result, err := engine.Calculate(name+"(1, 2)", nil); err != nil || result != 42 {
+			test.Errorf("%s => expected: 42, got: %v (%v)", name, result, err)
+		}
+	}
+}
+
+func TestVariableConversion(test *testing.T) {
+	vars := map[string]interface{}{
+		"price":    json.Number("19.90"),
+		"quantity": "3",
+		"enabled":  true,
+		"timeout":  90 * time.Second,
+	}
+
+	engine, _ := NewCalculationEngine()
+	if result, err := engine.Calculate("price * quantity * enabled", vars); err != nil || math.Abs(result-59.7) > 1e-9 {
+		test.Errorf("expected: 59.7, got: %v (%v)", result, err)
+	}
+
+	// a duration is a Value of its own, which 'Calculate' returns in seconds
+	if result, err := engine.Calculate("timeout", vars); err != nil || result != 90 {
+		test.Errorf("expected: 90, got: %v (%v)", result, err)
+	}
+
+	engine, _ = NewCalculationEngine(WithStrictConversion(true))
+
+	_, err := engine.Calculate("price * quantity", vars)
+
+	var typeErr *TypeError
+	if !errors.As(err, &typeErr) || typeErr.Token != "price" {
+		test.Errorf("expected: *TypeError, got: %v", err)
+	}
+
+	engine, _ = NewCalculationEngine(WithStrictConversion(true), WithTypeConverter(reflect.TypeOf(json.Number("")), func(value interface{}) (float64, error) {
+		return value.(json.Number).Float64()
+	}))
+
+	if result, err := engine.Calculate("price * 10", vars); err != nil || result != 199 {
+		test.Errorf("expected: 199, got: %v (%v)", result, err)
+	}
+
+	if _, err := NewCalculationEngine(WithTypeConverter(nil, nil)); err == nil {
+		test.Errorf("error should not be null")
+	}
+}
+
+func TestDates(test *testing.T) {
+	vars := map[string]interface{}{
+		"due":       time.Date(2024, 3, 15, 12, 0, 0, 0, time.UTC),
+		"birthdate": time.Date(1990, 6, 30, 0, 0, 0, 0, time.UTC),
+		"grace":     48 * time.Hour,
+		"n":         nil,
+	}
+
+	date := func(year int, month time.Month, day int) Value {
+		return DateValue(time.Date(year, month, day, 0, 0, 0, 0, time.UTC))
+	}
+
+	scenarios := []struct {
+		formula  string
+		expected Value
+	}{
+		{formula: "#2024-03-15# - #2024-03-01#", expected: DurationValue(14 * 24 * time.Hour)},
+		{formula: "due + grace", expected: DateValue(time.Date(2024, 3, 17, 12, 0, 0, 0, time.UTC))},
+		{formula: "grace + #2024-01-01#", expected: date(2024, 1, 3)},
+		{formula: "#2024-01-03# - grace", expected: date(2024, 1, 1)},
+		{formula: "grace * 1.5 - grace / 2", expected: DurationValue(48 * time.Hour)},
+		{formula: "-grace", expected: DurationValue(-48 * time.Hour)},
+		{formula: "(due - #2024-03-14T12:00:00Z#) / grace", expected: NumberValue(0.5)},
+		{formula: "due > #2024-03-15#", expected: NumberValue(1)},
+		{formula: "#2024-03-15T14:00:00+02:00# == #2024-03-15T12:00:00Z#", expected: NumberValue(1)},
+		{formula: "grace >= due - #2024-03-14#", expected: NumberValue(1)},
+		{formula: `"abc" < "abd"`, expected: NumberValue(1)},
+		{formula: "date(2024, 2, 29)", expected: date(2024, 2, 29)},
+		{formula: "date(2024, 2, 29, 13, 30, 15)", expected: DateValue(time.Date(2024, 2, 29, 13, 30, 15, 0, time.UTC))},
+		{formula: "year(due) * 10000 + month(due) * 100 + day(due)", expected: NumberValue(20240315)},
+		{formula: "weekday(#2024-03-17#)", expected: NumberValue(0)},
+		{formula: `datediff("year", birthdate, #2024-06-29#)`, expected: NumberValue(33)},
+		{formula: `datediff("years", birthdate, #2024-06-30#)`, expected: NumberValue(34)},
+		{formula: `datediff("month", #2024-01-31#, #2024-02-29#)`, expected: NumberValue(1)},
+		{formula: `datediff("month", #2024-03-31#, #2024-01-31#)`, expected: NumberValue(-2)},
+		{formula: `datediff("day", #2024-03-16#, due)`, expected: NumberValue(0)},
+		{formula: `datediff("Day", #2024-03-01#, due)`, expected: NumberValue(14)},
+		{formula: `datediff("hour", #2024-03-15#, due)`, expected: NumberValue(12)},
+		{formula: `dateadd("month", 1, #2024-01-31#)`, expected: date(2024, 2, 29)},
+		{formula: `dateadd("year", -1, #2024-02-29#)`, expected: date(2023, 2, 28)},
+		{formula: `dateadd("day", 1.5, #2024-01-01#)`, expected: DateValue(time.Date(2024, 1, 2, 12, 0, 0, 0, time.UTC))},
+		{formula: `dateadd("weeks", 2, #2024-01-01#)`, expected: date(2024, 1, 15)},
+		{formula: "eomonth(due)", expected: date(2024, 3, 31)},
+		{formula: "eomonth(#2024-01-15#, 1)", expected: date(2024, 2, 29)},
+		{formula: "eomonth(#2024-01-15#, -2)", expected: date(2023, 11, 30)},
+		{formula: "year(n)", expected: NullValue()},
+		{formula: "due - n", expected: NullValue()},
+		{formula: "if(due > #2024-01-01#, due, n)", expected: DateValue(time.Date(2024, 3, 15, 12, 0, 0, 0, time.UTC))},
+		{formula: "coalesce(n, due)", expected: DateValue(time.Date(2024, 3, 15, 12, 0, 0, 0, time.UTC))},
+	}
+
+	for _, optimizeEnabled := range []bool{true, false} {
+		engine, _ := NewCalculationEngine(WithOptimizeEnabled(optimizeEnabled))
+
+		for _, scenario := range scenarios {
+			result, err := engine.CalculateValue(scenario.formula, vars)
+			if err != nil || !result.equals(scenario.expected) {
+				test.Errorf("%s => expected: %v, got: %v (%v)", scenario.formula, scenario.expected, result, err)
+			}
+		}
+	}
+}
+
+func TestDateErrors(test *testing.T) {
+	vars := map[string]interface{}{
+		"due":   time.Date(2024, 3, 15, 0, 0, 0, 0, time.UTC),
+		"grace": 48 * time.Hour,
+	}
+
+	scenarios := []struct {
+		formula string
+		target  interface{}
+		message string
+	}{
+		{formula: "due + due", target: &TypeError{}, message: "the operator '+' cannot be applied to a date and a date at position 4"},
+		{formula: "due * 2", target: &TypeError{}, message: "the operator '*' cannot be applied to a date and a number"},
+		{formula: "grace < 1", target: &TypeError{}, message: "the operator '<' cannot be applied to a duration and a number"},
+		{formula: `due == "2024-03-15"`, target: &TypeError{}, message: "the operator '==' cannot be applied to a date and a string"},
+		{formula: "-due", target: &TypeError{}, message: "the operator '-' cannot be applied to a date"},
+		{formula: "due && 1", target: &TypeError{}, message: "the operator '&&' cannot be applied to a date and a number"},
+		{formula: "grace / 0", target: &ArithmeticError{}, message: "division produced"},
+		{formula: "sin(due)", target: &FunctionError{}, message: "the argument 1 is a date, expected a number"},
+		{formula: "year(1)", target: &FunctionError{}, message: "the argument 1 is a number, expected a date"},
+		{formula: `datediff("decade", due, due)`, target: &FunctionError{}, message: "unknown unit 'decade'"},
+		{formula: `dateadd("month", 1.5, due)`, target: &FunctionError{}, message: "the argument 2 is 1.5, expected an integer"},
+		{formula: "date(2024, 1)", target: &FunctionError{}, message: "expected between 3 and 6 arguments, got 2"},
+		{formula: "if(due, 1, 2)", target: &FunctionError{}, message: "the condition is a date"},
+	}
+
+	engine, _ := NewCalculationEngine()
+
+	for _, scenario := range scenarios {
+		_, err := engine.CalculateValue(scenario.formula, vars)
+		if err == nil || reflect.TypeOf(err) != reflect.TypeOf(scenario.target) || !strings.Contains(err.Error(), scenario.message) {
+			test.Errorf("%s => expected: %T containing %q, got: %v", scenario.formula, scenario.target, scenario.message, err)
+		}
+	}
+
+	if _, err := engine.Calculate(`"text"`, nil); err == nil {
+		test.Errorf("error should not be null")
+	}
+}
+
+func TestDateResultAsFloat(test *testing.T) {
+	engine, _ := NewCalculationEngine()
+
+	seconds, err := engine.Calculate("#2024-01-02# - #2024-01-01#", nil)
+	if err != nil || seconds != 86400 {
+		test.Errorf("expected: 86400, got: %v (%v)", seconds, err)
+	}
+
+	seconds, err = engine.Calculate("#1970-01-01T00:01:00Z#", nil)
+	if err != nil || seconds != 60 {
+		test.Errorf("expected: 60, got: %v (%v)", seconds, err)
+	}
+}
+
+func TestDateFunctionsCanBeOverwritten(test *testing.T) {
+	engine, _ := NewCalculationEngine()
+
+	engine.AddFunction("year", func(arguments ...interface{}) float64 {
+		return 2000
+	}, true)
+
+	if result, err := engine.Calculate("year(1)", nil); err != nil || result != 2000 {
+		test.Errorf("expected: 2000, got: %v (%v)", result, err)
+	}
+}
+
+func TestNowIsNotOptimized(test *testing.T) {
+	engine, _ := NewCalculationEngine()
+	evaluator, _ := engine.BuildEvaluator("now()")
+
+	before := time.Now()
+	result, err := evaluator.EvalValue(nil)
+	date, ok := result.Time()
+
+	if err != nil || !ok || date.Before(before.Add(-time.Second)) {
+		test.Errorf("expected: the current time, got: %v (%v)", result, err)
+	}
+}
+
+func TestStatisticsPack(test *testing.T) {
+	engine, _ := NewCalculationEngine(WithFunctionPack(StatisticsPack, StatisticsPack))
+
+	vars := map[string]interface{}{
+		"a": 4,
+		"b": 8,
+	}
+
+	scenarios := []struct {
+		formula  string
+		expected float64
+	}{
+		{formula: "avg(2, a, b, 6)", expected: 5},
+		{formula: "median(3, 1, 2)", expected: 2},
+		{formula: "median(4, 1, 3, 2)", expected: 2.5},
+		{formula: "mode(3, 1, 3, 2, 1)", expected: 1},
+		{formula: "mode(5)", expected: 5},
+		{formula: "var(2, 4, 4, 4, 5, 5, 7, 9)", expected: 32.0 / 7},
+		{formula: "varp(2, 4, 4, 4, 5, 5, 7, 9)", expected: 4},
+		{formula: "stdev(2, 4, 4, 4, 5, 5, 7, 9)", expected: math.Sqrt(32.0 / 7)},
+		{formula: "stdevp(2, 4, 4, 4, 5, 5, 7, 9)", expected: 2},
+		{formula: "percentile(25, 1, 2, 3, 4, 5)", expected: 2},
+		{formula: "percentile(90, 1, 2, 3, 4)", expected: 3.7},
+		{formula: "quantile(0.5, 10, 30, 20)", expected: 20},
+		{formula: "quantile(1, 10, 30, 20)", expected: 30},
+		{formula: "geomean(2, 8)", expected: 4},
+		{formula: "harmean(1, 4, 4)", expected: 2},
+		{formula: "sumsq(a, 3)", expected: 25},
+		{formula: "correl(1, 2, 3, 2, 4, 6)", expected: 1},
+		{formula: "correl(1, 2, 3, 3, 2, 1)", expected: -1},
+		{formula: "max(1, 2) + avg(3)", expected: 5},
+	}
+
+	for _, scenario := range scenarios {
+		result, err := engine.Calculate(scenario.formula, vars)
+		if err != nil || math.Abs(result-scenario.expected) > 1e-12 {
+			test.Errorf("%s => expected: %v, got: %v (%v)", scenario.formula, scenario.expected, result, err)
+		}
+	}
+
+	if fn, _ := engine.functionRegistry.get("stdev"); !fn.isIdempotent {
+		test.Errorf("expected: idempotent function")
+	}
+}
+
+func TestStatisticsPackErrors(test *testing.T) {
+	engine, _ := NewCalculationEngine(WithFunctionPack(StatisticsPack))
+
+	scenarios := []struct {
+		formula       string
+		notEnoughData bool
+		message       string
+	}{
+		{formula: "avg()", notEnoughData: true, message: "not enough values: expected at least 1, got 0"},
+		{formula: "stdev(1)", notEnoughData: true, message: "expected at least 2, got 1"},
+		{formula: "percentile(50)", notEnoughData: true, message: "got 0"},
+		{formula: "correl(1, 2)", notEnoughData: true, message: "expected at least 4, got 2"},
+		{formula: "correl(1, 2, 3)", message: "expected two series of the same length, got 3 values"},
+		{formula: "correl(1, 1, 2, 3)", message: "constant series"},
+		{formula: "percentile(101, 1, 2)", message: "between 0 and 100"},
+		{formula: "quantile(-0.5, 1, 2)", message: "between 0 and 1"},
+		{formula: "geomean(1, 0)", message: "the values must be positive, got 0"},
+		{formula: "percentile()", message: "expected at least 1 argument(s), got 0"},
+	}
+
+	for _, scenario := range scenarios {
+		_, err := engine.Calculate(scenario.formula, nil)
+
+		var functionError *FunctionError
+		if !errors.As(err, &functionError) || !strings.Contains(err.Error(), scenario.message) ||
+			errors.Is(err, ErrNotEnoughValues) != scenario.notEnoughData {
+			test.Errorf("%s => expected: error containing %q, got: %v", scenario.formula, scenario.message, err)
+		}
+	}
+}
+
+func TestFunctionPackIsOptIn(test *testing.T) {
+	engine, _ := NewCalculationEngine()
+
+	if _, err := engine.Calculate("stdevp(1, 3)", nil); err == nil {
+		test.Errorf("error should not be null")
+	}
+
+	if _, err := NewCalculationEngine(WithFunctionPack(FunctionPack(0))); err == nil {
+		test.Errorf("error should not be null")
+	}
+
+	engine, _ = NewCalculationEngine(WithDefaultFunctions(false), WithFunctionPack(StatisticsPack))
+
+	if result, err := engine.Calculate("avg(1, 2) + stdevp(1, 3)", nil); err != nil || result != 2.5 {
+		test.Errorf("expected: 2.5, got: %v (%v)", result, err)
+	}
+}
+
+func TestFinancialPack(test *testing.T) {
+	engine, _ := NewCalculationEngine(WithFunctionPack(FinancialPack))
+
+	// the expected values are computed by Excel, the ones of db are rounded to cents
+	scenarios := []struct {
+		formula  string
+		expected float64
+		delta    float64
+	}{
+		{formula: "pmt(0.08/12, 10, 10000)", expected: -1037.03208935915, delta: 1e-6},
+		{formula: "pmt(0, 10, 1000)", expected: -100, delta: 1e-6},
+		{formula: "ipmt(0.1/12, 1, 36, 8000)", expected: -66.6666666666667, delta: 1e-6},
+		{formula: "ipmt(0.1, 3, 3, 8000)", expected: -292.447129909366, delta: 1e-6},
+		{formula: "ppmt(0.1/12, 1, 24, 2000)", expected: -75.6231860083666, delta: 1e-6},
+		{formula: "fv(0.06/12, 10, -200, -500, 1)", expected: 2581.40337406012, delta: 1e-6},
+		{formula: "pv(0.08/12, 12*20, 500, 0)", expected: -59777.1458511878, delta: 1e-6},
+		{formula: "nper(0.12/12, -100, -1000, 10000, 1)", expected: 59.6738656742946, delta: 1e-6},
+		{formula: "rate(4*12, -200, 8000)", expected: 0.00770147248820137, delta: 1e-6},
+		{formula: "npv(0.1, -10000, 3000, 4200, 6800)", expected: 1188.44341233522, delta: 1e-6},
+		{formula: "irr(-70000, 12000, 15000, 18000, 21000, 26000)", expected: 0.0866309480365316, delta: 1e-6},
+		{formula: "xnpv(0.09, -10000, 2750, 4250, 3250, 2750, #2008-01-01#, #2008-03-01#, #2008-10-30#, #2009-02-15#, #2009-04-01#)", expected: 2086.64760203218, delta: 1e-6},
+		{formula: "sln(30000, 7500, 10)", expected: 2250, delta: 1e-6},
+		{formula: "db(1000000, 100000, 6, 1, 7)", expected: 186083.33, delta: 0.005},
+		{formula: "db(1000000, 100000, 6, 2, 7)", expected: 259639.42, delta: 0.005},
+		{formula: "db(1000000, 100000, 6, 7, 7)", expected: 15845.10, delta: 0.005},
+	}
+
+	for _, scenario := range scenarios {
+		result, err := engine.Calculate(scenario.formula, nil)
+		if err != nil || math.Abs(result-scenario.expected) > scenario.delta {
+			test.Errorf("%s => expected: %v, got: %v (%v)", scenario.formula, scenario.expected, result, err)
+		}
+	}
+}
+
+func TestFinancialPackErrors(test *testing.T) {
+	engine, _ := NewCalculationEngine(WithFunctionPack(FinancialPack), WithSolverMaxIterations(2))
+
+	_, err := engine.Calculate("irr(-70000, 12000, 15000, 18000, 21000, 26000)", nil)
+
+	var functionError *FunctionError
+	if !errors.As(err, &functionError) || !errors.Is(err, ErrNoConvergence) || functionError.Name != "irr" {
+		test.Errorf("expected: ErrNoConvergence, got: %v", err)
+	}
+
+	engine, _ = NewCalculationEngine(WithFunctionPack(FinancialPack))
+
+	scenarios := []struct {
+		formula string
+		message string
+	}{
+		{formula: "irr(100, 200)", message: "expected at least one positive and one negative value"},
+		{formula: "irr(-100)", message: "not enough values"},
+		{formula: "ipmt(0.1, 4, 3, 8000)", message: "the period must be between 1 and 3, got 4"},
+		{formula: "db(1000, 100, 5, 7)", message: "the period must be an integer between 1 and 5, got 7"},
+		{formula: "xnpv(0.1, 100, 200, #2024-01-01#)", message: "expected as many dates as values"},
+		{formula: "xnpv(0.1, 100, 2024)", message: "the argument 3 is a number, expected a date"},
+		{formula: "rate(10, 100, 1000)", message: "does not converge"},
+	}
+
+	for _, scenario := range scenarios {
+		_, err := engine.Calculate(scenario.formula, nil)
+		if !errors.As(err, &functionError) || !strings.Contains(err.Error(), scenario.message) {
+			test.Errorf("%s => expected: error containing %q, got: %v", scenario.formula, scenario.message, err)
+		}
+	}
+
+	for _, option := range []JaceOptions{WithSolverTolerance(0), WithSolverTolerance(math.NaN()), WithSolverMaxIterations(0)} {
+		if _, err := NewCalculationEngine(option); err == nil {
+			test.Errorf("error should not be null")
+		}
+	}
+}
+
+func TestJaceFunctions(test *testing.T) {
+	engine, _ := NewCalculationEngine()
+
+	// ported from the tests of Jace.NET
+	scenarios := []struct {
+		formula  string
+		expected float64
+	}{
+		{formula: "abs(-1.5)", expected: 1.5},
+		{formula: "sign(-3) + sign(0) * 10 + sign(2) * 100", expected: 99},
+		{formula: "exp(1)", expected: math.E},
+		{formula: "loge(3)", expected: math.Log(3)},
+		{formula: "log10(3)", expected: math.Log10(3)},
+		{formula: "logn(14, 3)", expected: math.Log(14) / math.Log(3)},
+		{formula: "logn(1, 0)", expected: 0},
+		{formula: "sum(1, 2, 3, 4)", expected: 10},
+		{formula: "sum()", expected: 0},
+		{formula: "avg(1, 2, 3, 4)", expected: 2.5},
+		{formula: "median(3, 1, 5, 4)", expected: 3.5},
+		{formula: "ceiling(1.2) + truncate(-1.7)", expected: 1},
+		{formula: "ifless(0.57, (3000-500)/(1500-500), 10, 20)", expected: 10},
+		{formula: "ifmore(0.57, (3000-500)/(1500-500), 10, 20)", expected: 20},
+		{formula: "ifequal(0.57, (3000-500)/(1500-500), 10, 20)", expected: 20},
+		{formula: "ifequal(2.5, (3000-500)/(1500-500), 10, 20)", expected: 10},
+		{formula: "csc(0.5)", expected: 1 / math.Sin(0.5)},
+		{formula: "sec(0.5)", expected: 1 / math.Cos(0.5)},
+		{formula: "cot(0.5)", expected: 1 / math.Tan(0.5)},
+		{formula: "acot(0.5)", expected: math.Atan(2)},
+		{formula: "asec(2)", expected: math.Acos(0.5)},
+		{formula: "acsc(2)", expected: math.Asin(0.5)},
+		{formula: "sinh(0.5)", expected: math.Sinh(0.5)},
+		{formula: "cosh(0.5)", expected: math.Cosh(0.5)},
+		{formula: "tanh(0.5)", expected: math.Tanh(0.5)},
+		{formula: "coth(0.5)", expected: 1 / math.Tanh(0.5)},
+		{formula: "sech(0.5)", expected: 1 / math.Cosh(0.5)},
+		{formula: "csch(0.5)", expected: 1 / math.Sinh(0.5)},
+		{formula: "asinh(0.5)", expected: math.Asinh(0.5)},
+		{formula: "acosh(2)", expected: math.Acosh(2)},
+		{formula: "atanh(0.5)", expected: math.Atanh(0.5)},
+		{formula: "acoth(2)", expected: math.Atanh(0.5)},
+		{formula: "asech(0.5)", expected: math.Acosh(2)},
+		{formula: "acsch(2)", expected: math.Asinh(0.5)},
+	}
+
+	for _, scenario := range scenarios {
+		result, err := engine.Calculate(scenario.formula, nil)
+		if err != nil || math.Abs(result-scenario.expected) > 1e-12 {
+			test.Errorf("%s => expected: %v, got: %v (%v)", scenario.formula, scenario.expected, result, err)
+		}
+	}
+
+	for _, formula := range []string{"logn(2, 1)", "logn(2, 0)", "logn(-1, 10)"} {
+		if result, err := engine.Calculate(formula, nil); err != nil || !math.IsNaN(result) {
+			test.Errorf("%s => expected: NaN, got: %v (%v)", formula, result, err)
+		}
+	}
+
+	if _, err := engine.Calculate("sign(a)", map[string]interface{}{"a": math.NaN()}); err == nil {
+		test.Errorf("error should not be null")
+	}
+
+	engine.AddFunction("sum", func(arguments ...interface{}) float64 { return 42 }, true)
+
+	if result, _ := engine.Calculate("sum(1, 2)", nil); result != 42 {
+		test.Errorf("expected: 42, got: %v", result)
+	}
+}
+
+func TestExcelDialect(test *testing.T) {
+	engine, _ := NewCalculationEngine(WithDialect(Excel))
+
+	vars := map[string]interface{}{
+		"a":    2,
+		"name": nil,
+	}
+
+	scenarios := []struct {
+		formula  string
+		expected Value
+	}{
+		{formula: `=IF(A>1;"x";"y")`, expected: StringValue("x")},
+		{formula: ` = IF(A>5, "x", "y")`, expected: StringValue("y")},
+		{formula: "=A<>2", expected: NumberValue(0)},
+		{formula: "=A=2", expected: NumberValue(1)},
+		{formula: "A==2", expected: NumberValue(1)},
+		{formula: `="total: " & A * 10 & " units"`, expected: StringValue("total: 20 units")},
+		{formula: `="a" & 1 = "a1"`, expected: NumberValue(1)},
+		{formula: `="<" & name & ">"`, expected: StringValue("<>")},
+		{formula: "=TRUE + true + FALSE", expected: NumberValue(2)},
+		{formula: "=SUM(1;2;3)", expected: NumberValue(6)},
+		{formula: "=ROUNDUP(3.2;0) + ROUNDUP(-3.14159;3)", expected: NumberValue(4 - 3.142)},
+		{formula: "=ROUNDDOWN(3.7;0) + ROUNDDOWN(-3.14159;3)", expected: NumberValue(3 - 3.141)},
+		{formula: "=ROUNDUP(31415.92654;-2)", expected: NumberValue(31500)},
+		{formula: "=MOD(-3;2) * 10 + MOD(3;-2)", expected: NumberValue(9)},
+		{formula: "=POWER(2;10)", expected: NumberValue(1024)},
+		{formula: "=AND(1;A>1;TRUE) + OR(0;FALSE) * 10 + NOT(A=3) * 100", expected: NumberValue(101)},
+		{formula: "=IFERROR(1/0;-1)", expected: NumberValue(-1)},
+		{formula: "=IFERROR(MOD(1;0);-2)", expected: NumberValue(-2)},
+		{formula: "=IFERROR(missing * 2;-3)", expected: NumberValue(-3)},
+		{formula: "=IFERROR(A;-4)", expected: NumberValue(2)},
+	}
+
+	for _, scenario := range scenarios {
+		result, err := engine.CalculateValue(scenario.formula, vars)
+		if err != nil || result.Kind() != scenario.expected.Kind() || math.Abs(result.number-scenario.expected.number) > 1e-12 || result.data != scenario.expected.data {
+			test.Errorf("%s => expected: %v, got: %v (%v)", scenario.formula, scenario.expected, result, err)
+		}
+	}
+
+	if _, err := NewCalculationEngine(WithDialect(Dialect(5))); err == nil {
+		test.Errorf("error should not be null")
+	}
+}
+
+func TestExcelDialectIsOptIn(test *testing.T) {
+	engine, _ := NewCalculationEngine()
+
+	for _, formula := range []string{"=1", "1 <> 2", "1 = 1", `"a" & "b"`, "1; 2", "TRUE", "mod(3, 2)"} {
+		if _, err := engine.Calculate(formula, nil); err == nil {
+			test.Errorf("%s => error should not be null", formula)
+		}
+	}
+
+	engine, _ = NewCalculationEngine(WithDialect(Excel), WithMaxEvaluationSteps(3))
+
+	var maxEvaluationStepsError *MaxEvaluationStepsError
+	if _, err := engine.Calculate("IFERROR(1 + 2 + 3 + 4; 0)", nil); !errors.As(err, &maxEvaluationStepsError) {
+		test.Errorf("expected: MaxEvaluationStepsError, got: %v", err)
+	}
+}
+
+func TestSpecialMathPack(test *testing.T) {
+	engine, _ := NewCalculationEngine(WithFunctionPack(SpecialMathPack))
+
+	scenarios := []struct {
+		formula  string
+		expected float64
+	}{
+		{formula: "gamma(5)", expected: 24},
+		{formula: "gamma(0.5)", expected: math.Sqrt(math.Pi)},
+		{formula: "lgamma(-0.5)", expected: math.Log(2 * math.Sqrt(math.Pi))},
+		{formula: "beta(2, 3)", expected: 1.0 / 12},
+		{formula: "erf(0.5) + erfc(0.5)", expected: 1},
+		{formula: "erf(erfinv(0.3))", expected: 0.3},
+		{formula: "j0(1)", expected: math.J0(1)},
+		{formula: "j1(1)", expected: math.J1(1)},
+		{formula: "jn(2, 1)", expected: math.Jn(2, 1)},
+		{formula: "y0(1)", expected: math.Y0(1)},
+		{formula: "y1(1)", expected: math.Y1(1)},
+		{formula: "yn(2, 1)", expected: math.Yn(2, 1)},
+		{formula: "expm1(1e-10)", expected: 1e-10},
+		{formula: "log1p(1e-10)", expected: 1e-10},
+		{formula: "hypot(3, 4)", expected: 5},
+		{formula: "atan2(1, -1)", expected: 3 * math.Pi / 4},
+		{formula: "cbrt(-27)", expected: -3},
+		{formula: "fact(0) + fact(5)", expected: 121},
+		{formula: "fact(170)", expected: math.Gamma(171)},
+	}
+
+	for _, scenario := range scenarios {
+		result, err := engine.Calculate(scenario.formula, nil)
+		if err != nil || math.Abs(result-scenario.expected) > 1e-12*math.Max(1, math.Abs(scenario.expected)) {
+			test.Errorf("%s => expected: %v, got: %v (%v)", scenario.formula, scenario.expected, result, err)
+		}
+	}
+}
+
+func TestSpecialMathPackDomainErrors(test *testing.T) {
+	engine, _ := NewCalculationEngine(WithFunctionPack(SpecialMathPack))
+
+	scenarios := []struct {
+		formula string
+		message string
+	}{
+		{formula: "fact(-1)", message: "the argument must be a non-negative integer, got -1"},
+		{formula: "fact(2.5)", message: "got 2.5"},
+		{formula: "fact(171)", message: "overflows"},
+		{formula: "gamma(-2)", message: "cannot be zero or a negative integer"},
+		{formula: "lgamma(0)", message: "cannot be zero or a negative integer"},
+		{formula: "beta(0, 1)", message: "must be positive"},
+		{formula: "erfinv(1)", message: "between -1 and 1"},
+		{formula: "y0(0)", message: "must be positive"},
+		{formula: "yn(1.5, 1)", message: "the order must be an integer"},
+		{formula: "log1p(-1)", message: "greater than -1"},
+	}
+
+	for _, scenario := range scenarios {
+		_, err := engine.Calculate(scenario.formula, nil)
+
+		var functionError *FunctionError
+		if !errors.As(err, &functionError) || !errors.Is(err, ErrOutOfDomain) || !strings.Contains(err.Error(), scenario.message) {
+			test.Errorf("%s => expected: error containing %q, got: %v", scenario.formula, scenario.message, err)
+		}
+	}
+
+	if _, err := engine.Calculate("hypot(1)", nil); err == nil {
+		test.Errorf("error should not be null")
+	}
+}
+
+func TestIntegerPack(test *testing.T) {
+	engine, _ := NewCalculationEngine(WithFunctionPack(IntegerPack))
+
+	scenarios := []struct {
+		formula  string
+		expected float64
+	}{
+		{formula: "fact(0)", expected: 1},
+		{formula: "fact(18)", expected: 6402373705728000},
+		{formula: "ncr(52, 5)", expected: 2598960},
+		{formula: "ncr(50, 25)", expected: 126410606437752},
+		{formula: "ncr(5, 0)", expected: 1},
+		{formula: "npr(10, 3)", expected: 720},
+		{formula: "npr(5, 0)", expected: 1},
+		{formula: "gcd(12, 18)", expected: 6},
+		{formula: "gcd(-12, 18, 8)", expected: 2},
+		{formula: "gcd(0, 0)", expected: 0},
+		{formula: "lcm(4, 6)", expected: 12},
+		{formula: "lcm(-4, 6, 5)", expected: 60},
+		{formula: "lcm(4, 0)", expected: 0},
+		{formula: "isprime(2)", expected: 1},
+		{formula: "isprime(1)", expected: 0},
+		{formula: "isprime(-7)", expected: 0},
+		{formula: "isprime(9007199254740881)", expected: 1},
+		{formula: "isprime(9007199254740883)", expected: 0},
+		{formula: "fib(0)", expected: 0},
+		{formula: "fib(10)", expected: 55},
+		{formula: "fib(78)", expected: 8944394323791464},
+		{formula: "floordiv(7, 2)", expected: 3},
+		{formula: "floordiv(-7, 2)", expected: -4},
+		{formula: "floordiv(7, -2)", expected: -4},
+		{formula: "floordiv(-8, 2)", expected: -4},
+		{formula: "powmod(2, 10, 1000)", expected: 24},
+		{formula: "powmod(-2, 3, 5)", expected: 2},
+		{formula: "powmod(3, 0, 1)", expected: 0},
+		{formula: "powmod(2, 9007199254740992, 7)", expected: 4},
+		{formula: "digitsum(-1234)", expected: 10},
+		{formula: "fact(3) * 2", expected: 12},
+	}
+
+	for _, scenario := range scenarios {
+		result, err := engine.Calculate(scenario.formula, nil)
+		if err != nil || result != scenario.expected {
+			test.Errorf("%s => expected: %v, got: %v (%v)", scenario.formula, scenario.expected, result, err)
+		}
+	}
+}
+
+func TestIntegerPackErrors(test *testing.T) {
+	engine, _ := NewCalculationEngine(WithFunctionPack(IntegerPack))
+
+	scenarios := []struct {
+		formula string
+		target  error
+		message string
+	}{
+		{formula: "fact(19)", target: ErrIntegerOverflow, message: "exceeds 2^53"},
+		{formula: "ncr(61, 30)", target: ErrIntegerOverflow, message: "exceeds 2^53"},
+		{formula: "npr(30, 20)", target: ErrIntegerOverflow, message: "exceeds 2^53"},
+		{formula: "lcm(9007199254740881, 2)", target: ErrIntegerOverflow, message: "exceeds 2^53"},
+		{formula: "fib(79)", target: ErrIntegerOverflow, message: "fib(79) exceeds 2^53"},
+		{formula: "fact(2.5)", target: ErrOutOfDomain, message: "the argument 1 must be an integer"},
+		{formula: "gcd(4, 1e16)", target: ErrOutOfDomain, message: "the argument 2 must be an integer"},
+		{formula: "fact(-1)", target: ErrOutOfDomain, message: "got -1"},
+		{formula: "ncr(3, 4)", target: ErrOutOfDomain, message: "expected 0 <= k <= n"},
+		{formula: "powmod(2, -1, 5)", target: ErrOutOfDomain, message: "a non-negative exponent"},
+		{formula: "powmod(2, 1, 0)", target: ErrOutOfDomain, message: "a positive modulus"},
+	}
+
+	for _, scenario := range scenarios {
+		_, err := engine.Calculate(scenario.formula, nil)
+
+		var functionError *FunctionError
+		if !errors.As(err, &functionError) || !errors.Is(err, scenario.target) || !strings.Contains(err.Error(), scenario.message) {
+			test.Errorf("%s => expected: error containing %q, got: %v", scenario.formula, scenario.message, err)
+		}
+	}
+
+	_, err := engine.Calculate("floordiv(1, 0)", nil)
+	if err == nil || !strings.Contains(err.Error(), "division by zero") {
+		test.Errorf("floordiv(1, 0) => expected: division by zero, got: %v", err)
+	}
+}
+
+func TestIntegerPackWithSpecialMathPack(test *testing.T) {
+	engine, err := NewCalculationEngine(WithFunctionPack(SpecialMathPack, IntegerPack))
+	if err != nil {
+		test.Fatalf("expected: no error, got: %v", err)
+	}
+
+	result, err := engine.Calculate("fact(5) + gamma(5)", nil)
+	if err != nil || result != 144 {
+		test.Errorf("expected: 144, got: %v (%v)", result, err)
+	}
+
+	// the exact factorial of the IntegerPack wins, whatever the order of the packs
+	for _, packs := range [][]FunctionPack{{SpecialMathPack, IntegerPack}, {IntegerPack, SpecialMathPack}} {
+		engine, _ := NewCalculationEngine(WithFunctionPack(packs...))
+		if _, err := engine.Calculate("fact(20)", nil); !errors.Is(err, ErrIntegerOverflow) {
+			test.Errorf("%v => expected: ErrIntegerOverflow, got: %v", packs, err)
+		}
+	}
+
+	engine, _ = NewCalculationEngine(WithFunctionPack(SpecialMathPack))
+	if result, err := engine.Calculate("fact(20)", nil); err != nil || result != math.Gamma(21) {
+		test.Errorf("expected: %v, got: %v (%v)", math.Gamma(21), result, err)
+	}
+}
+
+func TestRandomFunctions(test *testing.T) {
+	engine, _ := NewCalculationEngine()
+
+	scenarios := []struct {
+		formula string
+		check   func(result float64) bool
+	}{
+		{formula: "random()", check: func(result float64) bool { return result >= 0 && result < 1 }},
+		{formula: "random(1)", check: func(result float64) bool { return result >= 0 && result < 1 }},
+		{formula: "randint(1, 6)", check: func(result float64) bool { return result >= 1 && result <= 6 && result == math.Trunc(result) }},
+		{formula: "randint(-3, -3)", check: func(result float64) bool { return result == -3 }},
+		{formula: "randn(5, 0)", check: func(result float64) bool { return result == 5 }},
+		{formula: "randn()", check: func(result float64) bool { return !math.IsNaN(result) }},
+		{formula: "choice(7)", check: func(result float64) bool { return result == 7 }},
+		{formula: "choice(1, 2, 3)", check: func(result float64) bool { return result == 1 || result == 2 || result == 3 }},
+	}
+
+	for _, scenario := range scenarios {
+		for idx := 0; idx < 20; idx++ {
+			result, err := engine.Calculate(scenario.formula, nil)
+			if err != nil || !scenario.check(result) {
+				test.Errorf("%s => unexpected result: %v (%v)", scenario.formula, result, err)
+				break
+			}
+		}
+	}
+
+	errorScenarios := []struct {
+		formula string
+		message string
+	}{
+		{formula: "randint(1.5, 6)", message: "the bounds must be integers"},
+		{formula: "randint(6, 1)", message: "the lower bound 6 is greater than the upper bound 1"},
+		{formula: "randn(0, -1)", message: "the standard deviation cannot be negative"},
+	}
+
+	for _, scenario := range errorScenarios {
+		_, err := engine.Calculate(scenario.formula, nil)
+
+		var functionError *FunctionError
+		if !errors.As(err, &functionError) || !strings.Contains(err.Error(), scenario.message) {
+			test.Errorf("%s => expected: error containing %q, got: %v", scenario.formula, scenario.message, err)
+		}
+	}
+
+	if result, _ := engine.Calculate("random() - random()", nil); result == 0 {
+		test.Errorf("expected: two different numbers")
+	}
+}
+
+func TestRandomSource(test *testing.T) {
+	formula := "random() + randint(1, 1000) + randn(0, 1) + choice(1, 2, 3)"
+
+	first, _ := NewCalculationEngine(WithRandomSource(rand.NewSource(42)))
+	second, _ := NewCalculationEngine(WithRandomSource(rand.NewSource(42)))
+
+	for idx := 0; idx < 5; idx++ {
+		expected, _ := first.Calculate(formula, nil)
+		result, _ := second.Calculate(formula, nil)
+		if result != expected {
+			test.Errorf("expected: %v, got: %v", expected, result)
+		}
+	}
+
+	if _, err := NewCalculationEngine(WithRandomSource(nil)); err == nil {
+		test.Errorf("error should not be null")
+	}
+}
+
+func TestRandomSeedContext(test *testing.T) {
+	engine, _ := NewCalculationEngine()
+	formula := "random() + randint(1, 1000) + randn(0, 1) + choice(1, 2, 3)"
+
+	ctx := ContextWithRandomSeed(context.Background(), 7)
+	expected, err := engine.CalculateContext(ctx, formula, nil)
+	if err != nil {
+		test.Fatalf("unexpected error: %v", err)
+	}
+
+	for idx := 0; idx < 5; idx++ {
+		if result, _ := engine.CalculateContext(ctx, formula, nil); result != expected {
+			test.Errorf("expected: %v, got: %v", expected, result)
+		}
+	}
+
+	other, _ := NewCalculationEngine(WithRandomSource(rand.NewSource(1)))
+	if result, _ := other.CalculateContext(ctx, formula, nil); result != expected {
+		test.Errorf("the seed of the context should take precedence, expected: %v, got: %v", expected, result)
+	}
+
+	if result, _ := engine.CalculateContext(ContextWithRandomSeed(context.Background(), 8), formula, nil); result == expected {
+		test.Errorf("expected: another result with another seed, got: %v", result)
+	}
+}
+
+func TestRandomConcurrentEvaluations(test *testing.T) {
+	engine, _ := NewCalculationEngine(WithRandomSource(rand.NewSource(42)))
+	formula, _ := engine.Build("randint(1, 6)")
+
+	done := make(chan error)
+	for worker := 0; worker < 8; worker++ {
+		go func() {
+			for idx := 0; idx < 100; idx++ {
+				if result, err := formula(nil); err != nil || result < 1 || result > 6 {
+					done <- fmt.Errorf("unexpected result: %v (%v)", result, err)
+					return
+				}
+			}
+			done <- nil
+		}()
+	}
+
+	for worker := 0; worker < 8; worker++ {
+		if err := <-done; err != nil {
+			test.Error(err)
+		}
+	}
+}
+
+func TestAngleUnits(test *testing.T) {
+	scenarios := []struct {
+		unit     AngleUnit
+		formula  string
+		expected float64
+	}{
+		{unit: Radians, formula: "sin(pi/2)", expected: 1},
+		{unit: Radians, formula: "sin(30°)", expected: 0.5},
+		{unit: Radians, formula: "atan(1)", expected: math.Pi / 4},
+		{unit: Degrees, formula: "sin(30)", expected: 0.5},
+		{unit: Degrees, formula: "sin(30°)", expected: 0.5},
+		{unit: Degrees, formula: "cos(60)", expected: 0.5},
+		{unit: Degrees, formula: "tan(45)", expected: 1},
+		{unit: Degrees, formula: "asin(0.5)", expected: 30},
+		{unit: Degrees, formula: "acos(0)", expected: 90},
+		{unit: Degrees, formula: "atan(1)", expected: 45},
+		{unit: Degrees, formula: "cot(45)", expected: 1},
+		{unit: Degrees, formula: "sec(60)", expected: 2},
+		{unit: Degrees, formula: "csc(30)", expected: 2},
+		{unit: Degrees, formula: "acot(1)", expected: 45},
+		{unit: Degrees, formula: "asec(2)", expected: 60},
+		{unit: Degrees, formula: "acsc(2)", expected: 30},
+		{unit: Degrees, formula: "sin(3630)", expected: 0.5},
+		{unit: Degrees, formula: "sin(rad(30))", expected: math.Sin(math.Pi / 6 * math.Pi / 180)},
+		{unit: Gradians, formula: "sin(100)", expected: 1},
+		{unit: Gradians, formula: "acos(-1)", expected: 200},
+		{unit: Gradians, formula: "cos(90°)", expected: 0},
+		{unit: Radians, formula: "rad(180)", expected: math.Pi},
+		{unit: Gradians, formula: "deg(pi)", expected: 180},
+	}
+
+	for _, scenario := range scenarios {
+		engine, _ := NewCalculationEngine(WithAngleUnit(scenario.unit))
+		result, err := engine.Calculate(scenario.formula, nil)
+		if err != nil || math.Abs(result-scenario.expected) > 1e-12 {
+			test.Errorf("%s in unit %d => expected: %v, got: %v (%v)", scenario.formula, scenario.unit, scenario.expected, result, err)
+		}
+	}
+}
+
+func TestAngleUnitsExactQuarterTurns(test *testing.T) {
+	engine, _ := NewCalculationEngine(WithAngleUnit(Degrees))
+
+	scenarios := []struct {
+		formula  string
+		expected float64
+	}{
+		{formula: "sin(180)", expected: 0},
+		{formula: "cos(90)", expected: 0},
+		{formula: "cos(-180)", expected: -1},
+		{formula: "sin(-90)", expected: -1},
+		{formula: "sin(450)", expected: 1},
+		{formula: "tan(180)", expected: 0},
+	}
+
+	for _, scenario := range scenarios {
+		result, err := engine.Calculate(scenario.formula, nil)
+		if err != nil || result != scenario.expected {
+			test.Errorf("%s => expected: %v, got: %v (%v)", scenario.formula, scenario.expected, result, err)
+		}
+	}
+
+	if result, _ := engine.Calculate("tan(90)", nil); !math.IsInf(result, 1) {
+		test.Errorf("tan(90) => expected: +Inf, got: %v", result)
+	}
+
+	if _, err := NewCalculationEngine(WithAngleUnit(AngleUnit(7))); err == nil {
+		test.Errorf("error should not be null")
+	}
+}
+
+func TestAngleUnitAtan2(test *testing.T) {
+	engine, _ := NewCalculationEngine(WithAngleUnit(Degrees), WithFunctionPack(SpecialMathPack))
+
+	if result, err := engine.Calculate("atan2(1, -1)", nil); err != nil || math.Abs(result-135) > 1e-12 {
+		test.Errorf("expected: 135, got: %v (%v)", result, err)
+	}
+}
+
+func TestUnits(test *testing.T) {
+	engine, _ := NewCalculationEngine(WithUnits(true))
+
+	scenarios := []struct {
+		formula string
+		number  float64
+		unit    string
+	}{
+		{formula: "5 km + 300 m", number: 5.3, unit: "km"},
+		{formula: "300 m + 5 km", number: 5300, unit: "m"},
+		{formula: "(10 m)/(2 s)", number: 5, unit: "m/s"},
+		{formula: "9.81 m/s^2 * 2 s", number: 19.62, unit: "m/s"},
+		{formula: "5 m * 3 s", number: 15, unit: "m*s"},
+		{formula: "2 / 5 s", number: 0.4, unit: "1/s"},
+		{formula: "5[N*m]", number: 5, unit: "N*m"},
+		{formula: "(4 m^2)^0.5", number: 2, unit: "m"},
+		{formula: "-5 m", number: -5, unit: "m"},
+		{formula: "5 m * x", number: 10, unit: "m"},
+		{formula: "speed[m/s] * 10 s", number: 30, unit: "m"},
+		{formula: "convert(36 km/h, \"m/s\")", number: 10, unit: "m/s"},
+		{formula: "convert(6 W*h, \"J\")", number: 21600, unit: "J"},
+	}
+
+	vars := map[string]interface{}{"x": 2, "speed": 3}
+
+	for _, s := range scenarios {
+		result, err := engine.CalculateValue(s.formula, vars)
+		if err != nil {
+			test.Errorf("formula: %s, unexpected error: %v", s.formula, err)
+			continue
+		}
+
+		number, unit, ok := result.Quantity()
+		if !ok || math.Abs(number-s.number) > 1e-9 || unit != s.unit {
+			test.Errorf("formula: %s, expected: %v %s, got: %v", s.formula, s.number, s.unit, result)
+		}
+	}
+}
+
+func TestUnitsDimensionlessResults(test *testing.T) {
+	engine, _ := NewCalculationEngine(WithUnits(true))
+
+	scenarios := []struct {
+		formula  string
+		expected float64
+	}{
+		{formula: "(5 km)/(2 m)", expected: 2500},
+		{formula: "60 min / 1 h", expected: 1},
+		{formula: "1 km == 1000 m", expected: 1},
+		{formula: "1 km > 999 m", expected: 1},
+		{formula: "1 lb < 1 kg", expected: 1},
+	}
+
+	for _, s := range scenarios {
+		result, err := engine.CalculateValue(s.formula, nil)
+		number, ok := result.Float64()
+		if err != nil || !ok || math.Abs(number-s.expected) > 1e-9 {
+			test.Errorf("formula: %s, expected: %v, got: %v (%v)", s.formula, s.expected, result, err)
+		}
+	}
+
+	if result, err := engine.Calculate("5 km + 300 m", nil); err != nil || math.Abs(result-5.3) > 1e-9 {
+		test.Errorf("expected: 5.3, got: %v (%v)", result, err)
+	}
+}
+
+func TestUnitsFunctionWithoutArguments(test *testing.T) {
+	engine, _ := NewCalculationEngine(WithUnits(true))
+
+	if result, err := engine.CalculateValue("random() + 1", nil); err != nil || result.IsNull() {
+		test.Errorf("expected: a number, got: %v (%v)", result, err)
+	}
+
+	if diagnostics := engine.Validate("random() + 1"); diagnostics != nil {
+		test.Errorf("expected: no diagnostics, got: %v", diagnostics)
+	}
+}
+
+func TestUnitsIncompatible(test *testing.T) {
+	engine, _ := NewCalculationEngine(WithUnits(true))
+
+	_, err := engine.Build("5 m + 3 s")
+
+	var typeErr *TypeError
+	if !errors.As(err, &typeErr) || typeErr.Code != ErrorCodeIncompatibleUnits || typeErr.Position != 4 {
+		test.Errorf("expected: *TypeError at 4, got: %v", err)
+	}
+
+	for _, formula := range []string{"2 + 5 m", "(4 m)^0.5", "5[foo]"} {
+		if _, err := engine.Build(formula); err == nil {
+			test.Errorf("formula: %s, error should not be null", formula)
+		}
+	}
+
+	if _, err := engine.Calculate("convert(5 m, \"s\")", nil); err == nil {
+		test.Errorf("error should not be null")
+	}
+
+	formula, err := engine.Build("x + 1 m")
+	if err != nil {
+		test.Fatalf("unexpected error: %v", err)
+	}
+
+	length, _ := QuantityValue(2, "km")
+	if result, err := formula(map[string]interface{}{"x": length}); err != nil || result != 2.001 {
+		test.Errorf("expected: 2.001, got: %v (%v)", result, err)
+	}
+
+	duration, _ := QuantityValue(2, "s")
+	if _, err := formula(map[string]interface{}{"x": duration}); !errors.As(err, &typeErr) || typeErr.Code != ErrorCodeIncompatibleUnits {
+		test.Errorf("expected: *TypeError, got: %v", err)
+	}
+}
+
+func TestUnitsDisabledByDefault(test *testing.T) {
+	engine, _ := NewCalculationEngine()
+
+	for _, formula := range []string{"5 km", "x[m]"} {
+		if _, err := engine.Build(formula); err == nil {
+			test.Errorf("formula: %s, error should not be null", formula)
+		}
+	}
+
+	if _, err := QuantityValue(1, "foo"); err == nil {
+		test.Errorf("error should not be null")
+	}
+}
+
+func TestImplicitMultiplication(test *testing.T) {
+	engine, _ := NewCalculationEngine(WithImplicitMultiplication(true))
+
+	scenarios := []struct {
+		formula  string
+		expected float64
+	}{
+		{formula: "2x", expected: 4},
+		{formula: "2pi r", expected: 6 * math.Pi},
+		{formula: "3(x+1)", expected: 9},
+		{formula: "(x)(r)", expected: 6},
+		{formula: "(1+2)x", expected: 6},
+		{formula: "x(r)", expected: 6},
+		{formula: "x y r", expected: 30},
+		{formula: "2sin(0)cos(0)", expected: 0},
+		{formula: "2 max(1, 2)", expected: 4},
+		{formula: "1/2x", expected: 1},
+		{formula: "2^3x", expected: 16},
+		{formula: "-2x", expected: -4},
+	}
+
+	vars := map[string]interface{}{"x": 2, "r": 3, "y": 5}
+
+	for _, s := range scenarios {
+		result, err := engine.Calculate(s.formula, vars)
+		if err != nil || math.Abs(result-s.expected) > 1e-12 {
+			test.Errorf("formula: %s, expected: %v, got: %v (%v)", s.formula, s.expected, result, err)
+		}
+	}
+
+	for _, formula := range []string{"x 2", "2 3", "sin x", "2e"} {
+		if _, err := engine.Build(formula); err == nil {
+			test.Errorf("formula: %s, error should not be null", formula)
+		}
+	}
+
+	engine, _ = NewCalculationEngine()
+	for _, formula := range []string{"2x", "3(x+1)", "(x)(r)"} {
+		if _, err := engine.Build(formula); err == nil {
+			test.Errorf("formula: %s, error should not be null", formula)
+		}
+	}
+}
+
+func TestImplicitMultiplicationDiagnostics(test *testing.T) {
+	engine, _ := NewCalculationEngine(WithImplicitMultiplication(true))
+
+	if diagnostics := engine.Validate("2x + 3(y)"); diagnostics != nil {
+		test.Errorf("expected: no diagnostics, got: %v", diagnostics)
+	}
+
+	diagnostics := engine.Validate("2x + sin y")
+	if len(diagnostics) != 1 || diagnostics[0].Code != ErrorCodeMissingLeftBracket || diagnostics[0].Position != 5 {
+		test.Errorf("expected: missing_left_bracket at 5, got: %v", diagnostics)
+	}
+}
+
+func TestCalculatorDialect(test *testing.T) {
+	engine, _ := NewCalculationEngine(WithDialect(Calculator))
+
+	scenarios := []struct {
+		formula  string
+		expected float64
+	}{
+		{formula: "5!", expected: 120},
+		{formula: "x! + (1+2)!", expected: 30},
+		{formula: "sin(0)!", expected: 1},
+		{formula: "-3!", expected: -6},
+		{formula: "-3 !", expected: -6},
+		{formula: "2^3!", expected: 64},
+		{formula: "3!^2", expected: 36},
+		{formula: "2^-3!", expected: 1.0 / 64},
+		{formula: "3!!", expected: 720},
+		{formula: "5!%", expected: 1.2},
+		{formula: "50%", expected: 0.5},
+		{formula: "price * 15%", expected: 30},
+		{formula: "15% - 2", expected: -1.85},
+		{formula: "-50%", expected: -0.5},
+		{formula: "(5)% * 2", expected: 0.1},
+		{formula: "50 % 7", expected: 1},
+		{formula: "10%(3)", expected: 1},
+		{formula: "5! == 120", expected: 1},
+		{formula: "5!=120", expected: 1},
+	}
+
+	vars := map[string]interface{}{"price": 200, "x": 4}
+
+	for _, s := range scenarios {
+		result, err := engine.Calculate(s.formula, vars)
+		if err != nil || math.Abs(result-s.expected) > 1e-12 {
+			test.Errorf("formula: %s, expected: %v, got: %v (%v)", s.formula, s.expected, result, err)
+		}
+	}
+
+	for _, formula := range []string{"2.5!", "(-1)!", "171!"} {
+		var arithmeticErr *ArithmeticError
+		if _, err := engine.Calculate(formula, nil); !errors.Is(err, ErrOutOfDomain) || !errors.As(err, &arithmeticErr) {
+			test.Errorf("formula: %s, expected: *ArithmeticError wrapping ErrOutOfDomain, got: %v", formula, err)
+		}
+	}
+
+	for _, formula := range []string{"!5", "%5", "5 + !"} {
+		if _, err := engine.Build(formula); err == nil {
+			test.Errorf("formula: %s, error should not be null", formula)
+		}
+	}
+
+	// the domain is checked whatever the policy
+	engine, _ = NewCalculationEngine(WithDialect(Calculator), WithNonFinitePolicy(SubstituteNonFinite))
+
+	var arithmeticErr *ArithmeticError
+	if _, err := engine.Calculate("x!", map[string]interface{}{"x": 2.5}); !errors.As(err, &arithmeticErr) || arithmeticErr.Operation != "factorial" || arithmeticErr.Position != 1 {
+		test.Errorf("expected: *ArithmeticError at 1, got: %v", err)
+	}
+}
+
+func TestCalculatorDialectIsOptIn(test *testing.T) {
+	engine, _ := NewCalculationEngine()
+
+	if _, err := engine.Calculate("5!", nil); err == nil {
+		test.Errorf("error should not be null")
+	}
+
+	if _, err := engine.Calculate("50%", nil); err == nil {
+		test.Errorf("error should not be null")
+	}
+}
+
+func TestBitwiseOperators(test *testing.T) {
+	engine, _ := NewCalculationEngine()
+
+	scenarios := []struct {
+		formula  string
+		expected float64
+	}{
+		{formula: "6 & 3", expected: 2},
+		{formula: "6 | 3", expected: 7},
+		{formula: "6 xor 3", expected: 5},
+		{formula: "6 XOR 3", expected: 5},
+		{formula: "~5", expected: -6},
+		{formula: "~-1", expected: 0},
+		{formula: "1 << 4", expected: 16},
+		{formula: "1 << 60", expected: 1 << 60},
+		{formula: "256 >> 4", expected: 16},
+		{formula: "-7 >> 1", expected: -4},
+		{formula: "5 << 1 << 1", expected: 20},
+		{formula: "7 // 2", expected: 3},
+		{formula: "-7 // 2", expected: -4},
+		{formula: "7.5 // 2", expected: 3},
+		{formula: "2 * 3 // 2", expected: 3},
+		{formula: "1 + 2 & 3", expected: 3},
+		{formula: "6 & 3 xor 1 | 8", expected: 11},
+		{formula: "flags & 4 != 0", expected: 1},
+		{formula: "2^~1", expected: 0.25},
+		{formula: "1 && 0 || 1", expected: 1},
+	}
+
+	for _, s := range scenarios {
+		result, err := engine.Calculate(s.formula, map[string]interface{}{"flags": 6})
+		if err != nil || result != s.expected {
+			test.Errorf("formula: %s, expected: %v, got: %v (%v)", s.formula, s.expected, result, err)
+		}
+	}
+
+	if result, err := engine.CalculateValue("n | 1", map[string]interface{}{"n": nil}); err != nil || !result.IsNull() {
+		test.Errorf("expected: null, got: %v (%v)", result, err)
+	}
+
+	errorScenarios := []struct {
+		formula  string
+		code     ErrorCode
+		position int
+	}{
+		{formula: "2.5 & 1", code: ErrorCodeInvalidOperand, position: 4},
+		{formula: "~x", code: ErrorCodeInvalidOperand, position: 0},
+		{formula: "1 << -1", code: ErrorCodeInvalidOperand, position: 2},
+		{formula: "2^60 | 1", code: ErrorCodeInvalidOperand, position: 5},
+		{formula: `"a" xor 1`, code: ErrorCodeTypeMismatch, position: 4},
+		{formula: `"a" // 1`, code: ErrorCodeTypeMismatch, position: 4},
+	}
+
+	for _, s := range errorScenarios {
+		_, err := engine.Calculate(s.formula, map[string]interface{}{"x": 2.5})
+
+		var typeErr *TypeError
+		if !errors.As(err, &typeErr) || typeErr.Code != s.code || typeErr.Position != s.position {
+			test.Errorf("formula: %s, expected: %s at %d, got: %v", s.formula, s.code, s.position, err)
+		}
+	}
+
+	// with case sensitive names, only the lower case 'xor' is reserved
+	engine, _ = NewCalculationEngine(WithCaseSensitive(true))
+	if result, err := engine.Calculate("XOR xor 3", map[string]interface{}{"XOR": 6}); err != nil || result != 5 {
+		test.Errorf("expected: 5, got: %v (%v)", result, err)
+	}
+}
+
+func TestIntegerLiterals(test *testing.T) {
+	engine, _ := NewCalculationEngine()
+
+	scenarios := []struct {
+		formula  string
+		expected float64
+	}{
+		{formula: "0xFF + 0b1010 + 0o17", expected: 280},
+		{formula: "2-0x10", expected: -14},
+		{formula: "flags & 0b100", expected: 4},
+		{formula: "max(0x10, 1_000)", expected: 1000},
+		{formula: "1_000.5 * 2", expected: 2001},
+		{formula: "010", expected: 10},
+	}
+
+	for _, s := range scenarios {
+		result, err := engine.Calculate(s.formula, map[string]interface{}{"flags": 6})
+		if err != nil || result != s.expected {
+			test.Errorf("formula: %s, expected: %v, got: %v (%v)", s.formula, s.expected, result, err)
+		}
+	}
+
+	_, err := engine.Calculate("1 + 0xFG", nil)
+
+	var syntaxErr *SyntaxError
+	if !errors.As(err, &syntaxErr) || syntaxErr.Code != ErrorCodeInvalidNumber || syntaxErr.Position != 4 || syntaxErr.Length != 4 {
+		test.Errorf("expected: invalid_number at 4, got: %v", err)
+	}
+
+	engine, _ = NewCalculationEngine(WithArgumentSeparator(';'))
+	if result, err := engine.Calculate("max(1_000.5; 0x10)", nil); err != nil || result != 1000.5 {
+		test.Errorf("expected: 1000.5, got: %v (%v)", result, err)
+	}
+
+	// like the decimal numbers, they can be followed by a degree sign, a factorial or a unit
+	if result, err := engine.Calculate("sin(0x1E°)", nil); err != nil || math.Abs(result-0.5) > 1e-12 {
+		test.Errorf("expected: 0.5, got: %v (%v)", result, err)
+	}
+
+	engine, _ = NewCalculationEngine(WithDialect(Calculator))
+	if result, err := engine.Calculate("-0x3!", nil); err != nil || result != -6 {
+		test.Errorf("expected: -6, got: %v (%v)", result, err)
+	}
+
+	engine, _ = NewCalculationEngine(WithUnits(true))
+	if result, err := engine.Calculate(`convert(0x10 km + 0b11 m, "m")`, nil); err != nil || result != 16003 {
+		test.Errorf("expected: 16003, got: %v (%v)", result, err)
+	}
+}
@@ -0,0 +1,210 @@
+package gojacego
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+/*
+	Registers the functions handling dates. Like the other functions, they return null when one of
+	their arguments is null.
+*/
+func registryDateFunctions(registry *functionRegistry) {
+
+	registerDateFunction(registry, "now", func(arguments []Value) (Value, error) {
+		return DateValue(time.Now().UTC()), nil
+	}, 0, 0, false)
+
+	registerDateFunction(registry, "date", withNullArguments(func(arguments []Value) (Value, error) {
+		parts := [6]int{}
+		for idx := range arguments {
+			part, err := integerArgument(arguments, idx)
+			if err != nil {
+				return nullValue, err
+			}
+			parts[idx] = part
+		}
+		return DateValue(time.Date(parts[0], time.Month(parts[1]), parts[2], parts[3], parts[4], parts[5], 0, time.UTC)), nil
+	}), 3, 6, true)
+
+	registerDateFunction(registry, "year", withNullArguments(func(arguments []Value) (Value, error) {
+		date, err := dateArgument(arguments, 0)
+		return NumberValue(float64(date.Year())), err
+	}), 1, 1, true)
+
+	registerDateFunction(registry, "month", withNullArguments(func(arguments []Value) (Value, error) {
+		date, err := dateArgument(arguments, 0)
+		return NumberValue(float64(date.Month())), err
+	}), 1, 1, true)
+
+	registerDateFunction(registry, "day", withNullArguments(func(arguments []Value) (Value, error) {
+		date, err := dateArgument(arguments, 0)
+		return NumberValue(float64(date.Day())), err
+	}), 1, 1, true)
+
+	// Sunday is 0
+	registerDateFunction(registry, "weekday", withNullArguments(func(arguments []Value) (Value, error) {
+		date, err := dateArgument(arguments, 0)
+		return NumberValue(float64(date.Weekday())), err
+	}), 1, 1, true)
+
+	registerDateFunction(registry, "datediff", withNullArguments(func(arguments []Value) (Value, error) {
+		unit, err := unitArgument(arguments, 0)
+		if err != nil {
+			return nullValue, err
+		}
+		from, err := dateArgument(arguments, 1)
+		if err != nil {
+			return nullValue, err
+		}
+		to, err := dateArgument(arguments, 2)
+		if err != nil {
+			return nullValue, err
+		}
+		return NumberValue(float64(dateDiff(unit, from, to))), nil
+	}), 3, 3, true)
+
+	registerDateFunction(registry, "dateadd", withNullArguments(func(arguments []Value) (Value, error) {
+		unit, err := unitArgument(arguments, 0)
+		if err != nil {
+			return nullValue, err
+		}
+		date, err := dateArgument(arguments, 2)
+		if err != nil {
+			return nullValue, err
+		}
+
+		if unit == "year" || unit == "month" {
+			count, err := integerArgument(arguments, 1)
+			if err != nil {
+				return nullValue, err
+			}
+			if unit == "year" {
+				count *= 12
+			}
+			return DateValue(monthsLater(date, count)), nil
+		}
+
+		count, ok := arguments[1].Float64()
+		if !ok {
+			return nullValue, fmt.Errorf("the argument 2 is a %s, expected a number", arguments[1].kind)
+		}
+		nanoseconds := count * float64(dateUnits[unit])
+		if math.IsNaN(nanoseconds) || math.Abs(nanoseconds) >= math.MaxInt64 {
+			return nullValue, fmt.Errorf("cannot add %v %s(s) to a date", count, unit)
+		}
+		return DateValue(date.Add(time.Duration(math.Round(nanoseconds)))), nil
+	}), 3, 3, true)
+
+	registerDateFunction(registry, "eomonth", withNullArguments(func(arguments []Value) (Value, error) {
+		date, err := dateArgument(arguments, 0)
+		if err != nil {
+			return nullValue, err
+		}
+
+		months := 0
+		if len(arguments) > 1 {
+			if months, err = integerArgument(arguments, 1); err != nil {
+				return nullValue, err
+			}
+		}
+
+		// the day 0 of a month is the last day of the previous one
+		return DateValue(time.Date(date.Year(), date.Month()+time.Month(months)+1, 0, 0, 0, 0, 0, date.Location())), nil
+	}), 1, 2, true)
+}
+
+// Registers a date function, which can be overwritten since a custom function may already have its name.
+func registerDateFunction(registry *functionRegistry, name string, function valueDelegate, minParameters int, maxParameters int, isIdempotent bool) {
+	registry.registerValueFunction(name, function, minParameters, maxParameters, true, isIdempotent)
+}
+
+// The units of 'datediff' and 'dateadd' that have a fixed duration.
+var dateUnits = map[string]time.Duration{
+	"week":   7 * 24 * time.Hour,
+	"day":    24 * time.Hour,
+	"hour":   time.Hour,
+	"minute": time.Minute,
+	"second": time.Second,
+}
+
+// Returns null when one of the arguments is null, otherwise calls [function].
+func withNullArguments(function valueDelegate) valueDelegate {
+	return func(arguments []Value) (Value, error) {
+		for _, argument := range arguments {
+			if argument.IsNull() {
+				return nullValue, nil
+			}
+		}
+		return function(arguments)
+	}
+}
+
+func dateArgument(arguments []Value, idx int) (time.Time, error) {
+	date, ok := arguments[idx].Time()
+	if !ok {
+		return date, fmt.Errorf("the argument %d is a %s, expected a date", idx+1, arguments[idx].kind)
+	}
+	return date, nil
+}
+
+func integerArgument(arguments []Value, idx int) (int, error) {
+	number, ok := arguments[idx].Float64()
+	if !ok {
+		return 0, fmt.Errorf("the argument %d is a %s, expected a number", idx+1, arguments[idx].kind)
+	}
+	if number != math.Trunc(number) || math.Abs(number) > math.MaxInt32 {
+		return 0, fmt.Errorf("the argument %d is %v, expected an integer", idx+1, number)
+	}
+	return int(number), nil
+}
+
+// Returns the unit, in singular, named by the argument (i.e. "day" or "days").
+func unitArgument(arguments []Value, idx int) (string, error) {
+	name, ok := arguments[idx].Text()
+	if !ok {
+		return "", fmt.Errorf("the argument %d is a %s, expected a unit like \"day\"", idx+1, arguments[idx].kind)
+	}
+
+	unit := strings.TrimSuffix(strings.ToLower(name), "s")
+	if _, found := dateUnits[unit]; !found && unit != "year" && unit != "month" {
+		return "", fmt.Errorf("unknown unit '%s', expected year, month, week, day, hour, minute or second", name)
+	}
+	return unit, nil
+}
+
+/*
+	Returns the number of whole units from [from] to [to], negative when [to] is before [from].
+	Years and months count the complete calendar months, i.e. one month from January 31 to February 28.
+*/
+func dateDiff(unit string, from time.Time, to time.Time) int64 {
+	if unit != "year" && unit != "month" {
+		return int64(to.Sub(from) / dateUnits[unit])
+	}
+
+	months := (to.Year()-from.Year())*12 + int(to.Month()-from.Month())
+	if months > 0 && monthsLater(from, months).After(to) {
+		months--
+	} else if months < 0 && monthsLater(from, months).Before(to) {
+		months++
+	}
+
+	if unit == "year" {
+		return int64(months / 12)
+	}
+	return int64(months)
+}
+
+// Adds [months] to [date], keeping the day within the resulting month (i.e. January 31 plus one month is February 28).
+func monthsLater(date time.Time, months int) time.Time {
+	firstDay := time.Date(date.Year(), date.Month()+time.Month(months), 1, date.Hour(), date.Minute(), date.Second(), date.Nanosecond(), date.Location())
+	lastDay := firstDay.AddDate(0, 1, -1).Day()
+
+	day := date.Day()
+	if day > lastDay {
+		day = lastDay
+	}
+	return firstDay.AddDate(0, 0, day-1)
+}
@@ -0,0 +1,247 @@
+package gojacego
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+/*
+	Diagnostic describes a problem found in a formula in a way that can be shown to the users,
+	either as plain text (see 'Format') or as JSON.
+
+	Position and Length are expressed in runes. Position is -1 when the problem cannot be
+	attributed to a part of the formula.
+*/
+type Diagnostic struct {
+	Code       ErrorCode `json:"code"`
+	Message    string    `json:"message"`
+	Position   int       `json:"position"`
+	Length     int       `json:"length"`
+	Token      string    `json:"token,omitempty"`
+	Suggestion string    `json:"suggestion,omitempty"`
+}
+
+/*
+	Create a diagnostic from an error returned while building or evaluating the formula [formulaText].
+	The variables [vars] given to the evaluation, if any, are used to suggest the right name of a
+	misspelled variable. When the error does not name the offending part of the formula, the Token
+	is taken from [formulaText].
+*/
+func (this *CalculationEngine) Diagnose(formulaText string, err error, vars map[string]interface{}) Diagnostic {
+
+	diagnostic := newDiagnostic(err)
+
+	if runes := []rune(formulaText); diagnostic.Token == "" && diagnostic.Position >= 0 && diagnostic.Length > 0 &&
+		diagnostic.Position+diagnostic.Length <= len(runes) {
+		diagnostic.Token = string(runes[diagnostic.Position : diagnostic.Position+diagnostic.Length])
+	}
+
+	switch diagnostic.Code {
+	case ErrorCodeUnknownFunction:
+		names := make([]string, 0, len(this.functionRegistry.functions))
+		for name := range this.functionRegistry.functions {
+			names = append(names, name)
+		}
+		diagnostic.Suggestion = suggestName(diagnostic.Token, names, *this.options.caseSensitive)
+	case ErrorCodeUnknownVariable:
+		names := make([]string, 0, len(vars)+len(this.constantRegistry.constants))
+		for name := range vars {
+			names = append(names, name)
+		}
+		for name := range this.constantRegistry.constants {
+			names = append(names, name)
+		}
+		diagnostic.Suggestion = suggestName(diagnostic.Token, names, *this.options.caseSensitive)
+	}
+
+	return diagnostic
+}
+
+/*
+	Verify the formula [formulaText] without evaluating it and return a diagnostic for every problem
+	found, ordered by position. Unlike 'Build', which stops at the first problem, reading and parsing
+	resume after each one. It returns nil when the formula is valid.
+*/
+func (this *CalculationEngine) Validate(formulaText string) []Diagnostic {
+
+	tokens, errs := this.newTokenReader().readAll(formulaText)
+	if len(tokens) > 0 {
+		errs = append(errs, this.newAstBuilder(nil).validate(tokens)...)
+	}
+
+	if len(errs) == 0 {
+		// the structure is valid, what remains (i.e. the nesting limit) is verified by the builder
+		if operation, err := this.newAstBuilder(nil).build(tokens); err != nil {
+			errs = append(errs, err)
+		} else if err := this.checkUnits(operation); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+
+	diagnostics := make([]Diagnostic, len(errs))
+	for i, err := range errs {
+		diagnostics[i] = this.Diagnose(formulaText, err, nil)
+	}
+
+	sort.SliceStable(diagnostics, func(i, j int) bool {
+		return diagnostics[i].Position < diagnostics[j].Position
+	})
+
+	return diagnostics
+}
+
+func newDiagnostic(err error) Diagnostic {
+
+	var syntaxErr *SyntaxError
+	var variableErr *UnknownVariableError
+	var typeErr *TypeError
+	var functionErr *FunctionError
+	var arithmeticErr *ArithmeticError
+	var tokensErr *MaxTokensError
+	var nestingErr *MaxNestingDepthError
+	var operationDepthErr *MaxOperationDepthError
+
+	switch {
+	case errors.As(err, &syntaxErr):
+		return Diagnostic{Code: syntaxErr.Code, Message: syntaxErr.Message, Position: syntaxErr.Position, Length: syntaxErr.Length, Token: syntaxErr.Token}
+	case errors.As(err, &variableErr):
+		return Diagnostic{Code: variableErr.Code,
+			Message:  fmt.Sprintf("the variable '%s' is not defined", variableErr.Name),
+			Position: variableErr.Position,
+			Length:   variableErr.Length,
+			Token:    variableErr.Name}
+	case errors.As(err, &typeErr):
+		return Diagnostic{Code: typeErr.Code, Message: typeErr.Message, Position: typeErr.Position, Length: typeErr.Length, Token: typeErr.Token}
+	case errors.As(err, &functionErr):
+		return Diagnostic{Code: functionErr.Code,
+			Message:  fmt.Sprintf("function '%s': %s", functionErr.Name, functionErr.Err.Error()),
+			Position: functionErr.Position,
+			Length:   functionErr.Length,
+			Token:    functionErr.Name}
+	case errors.As(err, &arithmeticErr):
+		message := fmt.Sprintf("%s produced %v", arithmeticErr.Operation, arithmeticErr.Value)
+		if arithmeticErr.Err != nil {
+			message = fmt.Sprintf("%s: %s", arithmeticErr.Operation, arithmeticErr.Err.Error())
+		}
+		return Diagnostic{Code: arithmeticErr.Code, Message: message, Position: arithmeticErr.Position, Length: arithmeticErr.Length}
+	case errors.As(err, &tokensErr):
+		return Diagnostic{Message: tokensErr.Error(), Position: tokensErr.Position}
+	case errors.As(err, &nestingErr):
+		return Diagnostic{Message: nestingErr.Error(), Position: nestingErr.Position, Length: 1}
+	case errors.As(err, &operationDepthErr):
+		return Diagnostic{Message: operationDepthErr.Error(), Position: operationDepthErr.Position, Length: 1}
+	}
+
+	return Diagnostic{Message: err.Error(), Position: -1}
+}
+
+/*
+	Render the diagnostic as plain text: the message, the formula [formulaText] with the offending
+	part underlined and the suggestion, if any.
+
+		error[unknown_function]: unknown function 'sqr'
+		  sqr(4) + 1
+		  ^^^
+		  did you mean 'sqrt'?
+*/
+func (this Diagnostic) Format(formulaText string) string {
+	var builder strings.Builder
+
+	builder.WriteString("error")
+	if this.Code != "" {
+		builder.WriteString("[" + string(this.Code) + "]")
+	}
+	builder.WriteString(": " + this.Message + "\n")
+
+	if this.Position >= 0 {
+		length := this.Length
+		if length < 1 {
+			length = 1
+		}
+
+		builder.WriteString("  " + formulaText + "\n")
+		builder.WriteString("  " + strings.Repeat(" ", minInt(this.Position, utf8.RuneCountInString(formulaText))) + strings.Repeat("^", length) + "\n")
+	}
+
+	if this.Suggestion != "" {
+		builder.WriteString("  did you mean '" + this.Suggestion + "'?\n")
+	}
+
+	return builder.String()
+}
+
+// Returns the candidate closest to the given name, or an empty string when none is close enough.
+func suggestName(name string, candidates []string, caseSensitive bool) string {
+	if !caseSensitive {
+		name = strings.ToLower(name)
+	}
+
+	// allows one edit for short names and roughly one edit every three characters for longer ones
+	maxDistance := utf8.RuneCountInString(name) / 3
+	if maxDistance < 1 {
+		maxDistance = 1
+	}
+
+	suggestion := ""
+	suggestionDistance := maxDistance + 1
+	for _, candidate := range candidates {
+		compared := candidate
+		if !caseSensitive {
+			compared = strings.ToLower(candidate)
+		}
+
+		if compared == name {
+			continue
+		}
+
+		// ties are broken alphabetically, so the suggestion does not depend on the order of the candidates
+		distance := editDistance(name, compared)
+		if distance < suggestionDistance || (distance == suggestionDistance && candidate < suggestion) {
+			suggestion = candidate
+			suggestionDistance = distance
+		}
+	}
+
+	return suggestion
+}
+
+// Levenshtein distance between two strings, counted in runes.
+func editDistance(a string, b string) int {
+	source := []rune(a)
+	target := []rune(b)
+
+	previous := make([]int, len(target)+1)
+	current := make([]int, len(target)+1)
+
+	for j := range previous {
+		previous[j] = j
+	}
+
+	for i := 1; i <= len(source); i++ {
+		current[0] = i
+		for j := 1; j <= len(target); j++ {
+			cost := 1
+			if source[i-1] == target[j-1] {
+				cost = 0
+			}
+			current[j] = minInt(minInt(previous[j]+1, current[j-1]+1), previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+
+	return previous[len(target)]
+}
+
+func minInt(a int, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,259 @@
+package gojacego
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestDiagnoseUnknownFunction(test *testing.T) {
+	engine, _ := NewCalculationEngine()
+
+	formula := "1 + sqr(4)"
+	_, err := engine.Calculate(formula, nil)
+
+	diagnostic := engine.Diagnose(formula, err, nil)
+
+	if diagnostic.Code != ErrorCodeUnknownFunction || diagnostic.Position != 4 || diagnostic.Length != 3 {
+		test.Errorf("expected: unknown_function at 4, got: %s at %d", diagnostic.Code, diagnostic.Position)
+	}
+
+	if diagnostic.Suggestion != "sqrt" {
+		test.Errorf("expected: sqrt, got: %s", diagnostic.Suggestion)
+	}
+
+	expected := "error[unknown_function]: unknown function 'sqr'\n" +
+		"  1 + sqr(4)\n" +
+		"      ^^^\n" +
+		"  did you mean 'sqrt'?\n"
+
+	if text := diagnostic.Format(formula); text != expected {
+		test.Errorf("expected: %q, got: %q", expected, text)
+	}
+}
+
+func TestDiagnoseUnknownVariable(test *testing.T) {
+	engine, _ := NewCalculationEngine()
+
+	vars := map[string]interface{}{
+		"price":    10,
+		"quantity": 2,
+	}
+
+	formula := "price * quantty"
+	_, err := engine.Calculate(formula, vars)
+
+	diagnostic := engine.Diagnose(formula, err, vars)
+
+	if diagnostic.Code != ErrorCodeUnknownVariable || diagnostic.Position != 8 || diagnostic.Length != 7 {
+		test.Errorf("expected: unknown_variable at 8, got: %s at %d", diagnostic.Code, diagnostic.Position)
+	}
+
+	if diagnostic.Suggestion != "quantity" {
+		test.Errorf("expected: quantity, got: %s", diagnostic.Suggestion)
+	}
+
+	data, _ := json.Marshal(diagnostic)
+	expected := `{"code":"unknown_variable","message":"the variable 'quantty' is not defined","position":8,"length":7,"token":"quantty","suggestion":"quantity"}`
+
+	if string(data) != expected {
+		test.Errorf("expected: %s, got: %s", expected, string(data))
+	}
+}
+
+func TestDiagnoseWithoutSuggestion(test *testing.T) {
+	engine, _ := NewCalculationEngine()
+
+	formula := "(1 + 2"
+	_, err := engine.Calculate(formula, nil)
+
+	diagnostic := engine.Diagnose(formula, err, nil)
+
+	expected := "error[missing_right_bracket]: no matching right bracket found for the left bracket\n" +
+		"  (1 + 2\n" +
+		"  ^\n"
+
+	if text := diagnostic.Format(formula); text != expected {
+		test.Errorf("expected: %q, got: %q", expected, text)
+	}
+
+	_, err = engine.Calculate("xyzzy(1)", nil)
+	if diagnostic := engine.Diagnose("xyzzy(1)", err, nil); diagnostic.Suggestion != "" {
+		test.Errorf("expected no suggestion, got: %s", diagnostic.Suggestion)
+	}
+}
+
+func TestDiagnoseTokenFromFormula(test *testing.T) {
+	engine, _ := NewCalculationEngine(WithNonFinitePolicy(ErrorOnNonFinite))
+
+	formula := "1 + x/0"
+	_, err := engine.Calculate(formula, map[string]interface{}{"x": 2})
+
+	diagnostic := engine.Diagnose(formula, err, nil)
+
+	if diagnostic.Code != ErrorCodeArithmetic || diagnostic.Position != 5 || diagnostic.Token != "/" {
+		test.Errorf("expected: arithmetic at 5 with the token '/', got: %s at %d with '%s'", diagnostic.Code, diagnostic.Position, diagnostic.Token)
+	}
+}
+
+func TestDiagnoseArithmeticErrorCause(test *testing.T) {
+	engine, _ := NewCalculationEngine(WithDialect(Calculator))
+
+	formula := "(-1)!"
+	_, err := engine.Calculate(formula, nil)
+
+	diagnostic := engine.Diagnose(formula, err, nil)
+
+	if diagnostic.Code != ErrorCodeArithmetic || !strings.Contains(diagnostic.Message, "out of domain") {
+		test.Errorf("expected: arithmetic with the cause, got: %s (%s)", diagnostic.Code, diagnostic.Message)
+	}
+}
+
+func TestSuggestName(test *testing.T) {
+	candidates := []string{"sin", "sqrt", "cos", "Total"}
+
+	scenarios := []struct {
+		name          string
+		caseSensitive bool
+		expected      string
+	}{
+		{name: "sqr", expected: "sqrt"},
+		{name: "sinn", expected: "sin"},
+		{name: "cso", expected: ""},
+		{name: "totl", expected: "Total"},
+		{name: "totl", caseSensitive: true, expected: ""},
+		{name: "abc", expected: ""},
+	}
+
+	for _, scenario := range scenarios {
+		if suggestion := suggestName(scenario.name, candidates, scenario.caseSensitive); suggestion != scenario.expected {
+			test.Errorf("%s => expected: '%s', got: '%s'", scenario.name, scenario.expected, suggestion)
+		}
+	}
+}
+
+func TestEditDistance(test *testing.T) {
+	scenarios := []struct {
+		a        string
+		b        string
+		expected int
+	}{
+		{a: "", b: "abc", expected: 3},
+		{a: "sqr", b: "sqrt", expected: 1},
+		{a: "kitten", b: "sitting", expected: 3},
+		{a: "≤≥", b: "≥", expected: 1},
+	}
+
+	for _, scenario := range scenarios {
+		if distance := editDistance(scenario.a, scenario.b); distance != scenario.expected {
+			test.Errorf("%s, %s => expected: %d, got: %d", scenario.a, scenario.b, scenario.expected, distance)
+		}
+	}
+}
+
+func TestValidate(test *testing.T) {
+	engine, _ := NewCalculationEngine()
+
+	type expectedDiagnostic struct {
+		code     ErrorCode
+		position int
+	}
+
+	scenarios := []struct {
+		formula  string
+		expected []expectedDiagnostic
+	}{
+		{formula: "1 + sin(x) * max(2, y)", expected: nil},
+		{formula: "1 # 2", expected: []expectedDiagnostic{{ErrorCodeInvalidToken, 2}}},
+		{formula: "1 + # + sqr(2) + (3", expected: []expectedDiagnostic{
+			{ErrorCodeInvalidToken, 4},
+			{ErrorCodeUnknownFunction, 8},
+			{ErrorCodeMissingRightBracket, 17},
+		}},
+		{formula: "max(1,,2) + 1 2", expected: []expectedDiagnostic{
+			{ErrorCodeMissingOperand, 5},
+			{ErrorCodeUnexpectedToken, 14},
+		}},
+		{formula: "sin() + cos(1, 2) + 0..1", expected: []expectedDiagnostic{
+			{ErrorCodeInvalidArguments, 0},
+			{ErrorCodeInvalidArguments, 8},
+			{ErrorCodeInvalidNumber, 20},
+		}},
+		{formula: "(1 + ) * 2) + 1e5e3", expected: []expectedDiagnostic{
+			{ErrorCodeMissingOperand, 3},
+			{ErrorCodeMissingLeftBracket, 10},
+			{ErrorCodeInvalidToken, 17},
+		}},
+		{formula: "sin + 1 +", expected: []expectedDiagnostic{
+			{ErrorCodeMissingLeftBracket, 0},
+			{ErrorCodeMissingOperand, 8},
+		}},
+	}
+
+	for _, scenario := range scenarios {
+		diagnostics := engine.Validate(scenario.formula)
+
+		if len(diagnostics) != len(scenario.expected) {
+			test.Errorf("%s => expected: %d diagnostics, got: %v", scenario.formula, len(scenario.expected), diagnostics)
+			continue
+		}
+
+		for i, expected := range scenario.expected {
+			if diagnostics[i].Code != expected.code || diagnostics[i].Position != expected.position {
+				test.Errorf("%s => expected: %s at %d, got: %s at %d", scenario.formula,
+					expected.code, expected.position, diagnostics[i].Code, diagnostics[i].Position)
+			}
+		}
+	}
+}
+
+func TestValidateSuggestions(test *testing.T) {
+	engine, _ := NewCalculationEngine()
+
+	diagnostics := engine.Validate("sqr(2) + coss(1)")
+
+	if len(diagnostics) != 2 || diagnostics[0].Suggestion != "sqrt" || diagnostics[1].Suggestion != "cos" {
+		test.Errorf("expected: suggestions 'sqrt' and 'cos', got: %v", diagnostics)
+	}
+}
+
+func TestValidateLimits(test *testing.T) {
+	engine, _ := NewCalculationEngine(WithMaxNestingDepth(2))
+
+	diagnostics := engine.Validate("((( 1 )))")
+
+	if len(diagnostics) != 1 || diagnostics[0].Position != 2 {
+		test.Errorf("expected: nesting depth exceeded at 2, got: %v", diagnostics)
+	}
+
+	engine, _ = NewCalculationEngine(WithMaxOperationDepth(1))
+
+	diagnostics = engine.Validate("1 + 2 + 3")
+
+	if len(diagnostics) != 1 || diagnostics[0].Position != 6 {
+		test.Errorf("expected: operation depth exceeded at 6, got: %v", diagnostics)
+	}
+}
+
+func TestValidateMatchesBuild(test *testing.T) {
+	engine, _ := NewCalculationEngine()
+
+	formulas := []string{"(1+2", "1+2)", "1+", "1 <= ", "1 + * 2", "*2", "1 2", "(1)(2)", "1,2",
+		"max(1,(2,3))", "max(1,)", "()", "foo(1)", "sin + 1", "1 + sin()", "a $ b", "1e5e3"}
+
+	for _, formula := range formulas {
+		_, err := engine.Build(formula)
+		expected := newDiagnostic(err)
+
+		diagnostics := engine.Validate(formula)
+		if len(diagnostics) == 0 {
+			test.Errorf("%s => expected: %s, got: no diagnostics", formula, expected.Code)
+			continue
+		}
+
+		if diagnostics[0].Code != expected.Code || diagnostics[0].Position != expected.Position {
+			test.Errorf("%s => expected: %s at %d, got: %s at %d", formula,
+				expected.Code, expected.Position, diagnostics[0].Code, diagnostics[0].Position)
+		}
+	}
+}
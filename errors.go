@@ -0,0 +1,198 @@
+package gojacego
+
+import (
+	"errors"
+	"fmt"
+)
+
+/*
+	ErrorCode identifies the kind of problem reported by an error.
+*/
+type ErrorCode string
+
+const (
+	ErrorCodeEmptyFormula        ErrorCode = "empty_formula"
+	ErrorCodeInvalidToken        ErrorCode = "invalid_token"
+	ErrorCodeInvalidNumber       ErrorCode = "invalid_number"
+	ErrorCodeInvalidDate         ErrorCode = "invalid_date"
+	ErrorCodeUnexpectedToken     ErrorCode = "unexpected_token"
+	ErrorCodeMissingOperand      ErrorCode = "missing_operand"
+	ErrorCodeMissingLeftBracket  ErrorCode = "missing_left_bracket"
+	ErrorCodeMissingRightBracket ErrorCode = "missing_right_bracket"
+	ErrorCodeUnknownFunction     ErrorCode = "unknown_function"
+	ErrorCodeInvalidArguments    ErrorCode = "invalid_arguments"
+	ErrorCodeFunctionFailed      ErrorCode = "function_failed"
+	ErrorCodeUnknownVariable     ErrorCode = "unknown_variable"
+	ErrorCodeTypeMismatch        ErrorCode = "type_mismatch"
+	ErrorCodeArithmetic          ErrorCode = "arithmetic"
+	ErrorCodeUnknownUnit         ErrorCode = "unknown_unit"
+	ErrorCodeIncompatibleUnits   ErrorCode = "incompatible_units"
+	ErrorCodeInvalidOperand      ErrorCode = "invalid_operand"
+)
+
+/*
+	SyntaxError is returned when a formula cannot be parsed. Position and Length are expressed
+	in runes and delimit the offending Token in the formula.
+*/
+type SyntaxError struct {
+	Code     ErrorCode
+	Message  string
+	Token    string
+	Position int
+	Length   int
+}
+
+func (this *SyntaxError) Error() string {
+	return fmt.Sprintf("%s at position %d", this.Message, this.Position)
+}
+
+/*
+	UnknownVariableError is returned when a formula uses a variable whose value was not provided.
+*/
+type UnknownVariableError struct {
+	Code     ErrorCode
+	Name     string
+	Position int
+	Length   int
+}
+
+func (this *UnknownVariableError) Error() string {
+	return fmt.Sprintf("the variable '%s' used at position %d is not defined", this.Name, this.Position)
+}
+
+/*
+	TypeError is returned when a value cannot be used where it appears in a formula
+	(i.e. a variable whose value cannot be converted to a number).
+*/
+type TypeError struct {
+	Code     ErrorCode
+	Message  string
+	Token    string
+	Position int
+	Length   int
+}
+
+func (this *TypeError) Error() string {
+	return fmt.Sprintf("%s at position %d", this.Message, this.Position)
+}
+
+/*
+	FunctionError is returned when a function is called with an invalid number of arguments or when it
+	fails during the evaluation of a formula. It carries the name of the function and the position of
+	the call in the formula.
+*/
+type FunctionError struct {
+	Code     ErrorCode
+	Name     string
+	Position int
+	Length   int
+	Err      error
+}
+
+func (this *FunctionError) Error() string {
+	return fmt.Sprintf("function '%s' at position %d: %s", this.Name, this.Position, this.Err.Error())
+}
+
+func (this *FunctionError) Unwrap() error {
+	return this.Err
+}
+
+/*
+	ArithmeticError is returned, when the engine is configured with 'ErrorOnNonFinite', by the first
+	operation of a formula that produces NaN or an infinity. Operation names the operator (i.e. "division"),
+	the function or the variable that produced the Value.
+	It is returned as well, whatever the policy, by an operator whose operand is out of its domain
+	(i.e. the factorial of 2.5). Err then wraps ErrOutOfDomain and Value is NaN.
+*/
+type ArithmeticError struct {
+	Code      ErrorCode
+	Operation string
+	Value     float64
+	Position  int
+	Length    int
+	Err       error
+}
+
+func (this *ArithmeticError) Error() string {
+	if this.Err != nil {
+		return fmt.Sprintf("%s at position %d: %s", this.Operation, this.Position, this.Err.Error())
+	}
+	return fmt.Sprintf("%s produced %v at position %d", this.Operation, this.Value, this.Position)
+}
+
+func (this *ArithmeticError) Unwrap() error {
+	return this.Err
+}
+
+/*
+	MaxFormulaLengthError is returned when a formula is longer than the limit set by 'WithMaxFormulaLength'.
+*/
+type MaxFormulaLengthError struct {
+	Length int
+	Limit  int
+}
+
+func (this *MaxFormulaLengthError) Error() string {
+	return fmt.Sprintf("the formula has %d characters, the limit is %d", this.Length, this.Limit)
+}
+
+/*
+	MaxTokensError is returned when a formula has more tokens than the limit set by 'WithMaxTokens'.
+*/
+type MaxTokensError struct {
+	Position int
+	Limit    int
+}
+
+func (this *MaxTokensError) Error() string {
+	return fmt.Sprintf("the formula exceeds the limit of %d tokens at position %d", this.Limit, this.Position)
+}
+
+/*
+	MaxNestingDepthError is returned when the brackets of a formula are nested deeper than the limit
+	set by 'WithMaxNestingDepth'.
+*/
+type MaxNestingDepthError struct {
+	Position int
+	Limit    int
+}
+
+func (this *MaxNestingDepthError) Error() string {
+	return fmt.Sprintf("the formula exceeds the nesting depth of %d at position %d", this.Limit, this.Position)
+}
+
+/*
+	MaxOperationDepthError is returned when the operator tree of a formula is deeper than the limit set
+	by 'WithMaxOperationDepth'.
+*/
+type MaxOperationDepthError struct {
+	Position int
+	Limit    int
+}
+
+func (this *MaxOperationDepthError) Error() string {
+	return fmt.Sprintf("the formula exceeds the operation depth of %d at position %d", this.Limit, this.Position)
+}
+
+/*
+	MaxEvaluationStepsError is returned when the evaluation of a formula takes more steps than the limit
+	set by 'WithMaxEvaluationSteps'.
+*/
+type MaxEvaluationStepsError struct {
+	Limit int
+}
+
+func (this *MaxEvaluationStepsError) Error() string {
+	return fmt.Sprintf("the evaluation exceeds the limit of %d steps", this.Limit)
+}
+
+// Converts a value recovered from a panic into an error.
+func recoveredError(r interface{}) error {
+	switch e := r.(type) {
+	case error:
+		return e
+	case string:
+		return errors.New(e)
+	}
+	return fmt.Errorf("%v", r)
+}
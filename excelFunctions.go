@@ -0,0 +1,89 @@
+package gojacego
+
+import (
+	"errors"
+	"math"
+)
+
+/*
+	Registers the constants of the Excel dialect.
+*/
+func registryExcelConstants(registry *constantRegistry) {
+	registry.registerConstant("TRUE", 1, false)
+	registry.registerConstant("FALSE", 0, false)
+}
+
+/*
+	Registers the functions of the Excel dialect that are not default functions.
+*/
+func registryExcelFunctions(registry *functionRegistry) {
+
+	registry.registerTypedFunction("roundup", func(arguments []float64) (float64, error) {
+		return roundMagnitude(arguments[0], arguments[1], math.Ceil), nil
+	}, 2, 2, false, true)
+
+	registry.registerTypedFunction("rounddown", func(arguments []float64) (float64, error) {
+		return roundMagnitude(arguments[0], arguments[1], math.Floor), nil
+	}, 2, 2, false, true)
+
+	// the result has the sign of the divisor
+	registry.registerTypedFunction("mod", func(arguments []float64) (float64, error) {
+		if arguments[1] == 0 {
+			return 0, errors.New("division by zero")
+		}
+		return arguments[0] - arguments[1]*math.Floor(arguments[0]/arguments[1]), nil
+	}, 2, 2, false, true)
+
+	registry.registerTypedFunction("power", func(arguments []float64) (float64, error) {
+		return math.Pow(arguments[0], arguments[1]), nil
+	}, 2, 2, false, true)
+
+	registry.registerTypedFunction("and", func(arguments []float64) (float64, error) {
+		for _, argument := range arguments {
+			if argument == 0 {
+				return 0, nil
+			}
+		}
+		return 1, nil
+	}, 1, unlimitedParameters, false, true)
+
+	registry.registerTypedFunction("or", func(arguments []float64) (float64, error) {
+		for _, argument := range arguments {
+			if argument != 0 {
+				return 1, nil
+			}
+		}
+		return 0, nil
+	}, 1, unlimitedParameters, false, true)
+
+	registry.registerTypedFunction("not", func(arguments []float64) (float64, error) {
+		if arguments[0] == 0 {
+			return 1, nil
+		}
+		return 0, nil
+	}, 1, 1, false, true)
+
+	// the errors and the NaN and infinite results of the value are replaced by the fallback
+	registry.registerLazyFunction("iferror", func(arguments []lazyArgument) (Value, error) {
+		value, err := arguments[0].try()
+		if err == nil && !(value.kind == KindNumber && (math.IsNaN(value.number) || math.IsInf(value.number, 0))) {
+			return value, nil
+		}
+		return arguments[1].value(), nil
+	}, 2, 2, false, true)
+}
+
+/*
+	Rounds the magnitude of [x] to [digits] decimal places with [rounding] (math.Ceil rounds away
+	from zero and math.Floor towards zero), ignoring the binary representation errors.
+*/
+func roundMagnitude(x float64, digits float64, rounding func(float64) float64) float64 {
+	pow := math.Pow(10, math.Trunc(digits))
+	magnitude := math.Abs(x) * pow
+
+	// i.e. 3.2 * 10 is 32.000000000000004
+	if nearest := math.Round(magnitude); math.Abs(magnitude-nearest) <= 1e-9*math.Max(1, magnitude) {
+		magnitude = nearest
+	}
+	return math.Copysign(rounding(magnitude)/pow, x)
+}
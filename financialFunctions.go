@@ -0,0 +1,300 @@
+package gojacego
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+)
+
+/*
+	ErrNoConvergence is returned, wrapped in a *FunctionError, by the iterative functions of the
+	FinancialPack ('rate' and 'irr') when they do not find a solution within the tolerance and the
+	number of iterations given to 'WithSolverTolerance' and 'WithSolverMaxIterations'.
+*/
+var ErrNoConvergence = errors.New("the calculation does not converge")
+
+const (
+	defaultSolverTolerance     = 1e-10
+	defaultSolverMaxIterations = 100
+)
+
+// Configures the iterative functions.
+type solverOptions struct {
+	tolerance     float64
+	maxIterations int
+}
+
+/*
+	Registers the functions of the FinancialPack, compatible with the ones of Excel. Like Excel, the
+	money paid out is negative and the payments are due at the end of the periods unless 'type' is 1.
+*/
+func registryFinancialFunctions(registry *functionRegistry, options *jaceOptions) {
+	solver := options.solver
+
+	// pmt(rate, nper, pv [, fv [, type]])
+	registry.registerTypedFunction("pmt", func(arguments []float64) (float64, error) {
+		return annuityPayment(arguments[0], arguments[1], arguments[2], optionalArgument(arguments, 3, 0), paymentType(arguments, 4)), nil
+	}, 3, 5, false, true)
+
+	// ipmt(rate, per, nper, pv [, fv [, type]])
+	registry.registerTypedFunction("ipmt", func(arguments []float64) (float64, error) {
+		if err := checkPeriod(arguments[1], arguments[2]); err != nil {
+			return 0, err
+		}
+		return interestPayment(arguments[0], arguments[1], arguments[2], arguments[3], optionalArgument(arguments, 4, 0), paymentType(arguments, 5)), nil
+	}, 4, 6, false, true)
+
+	// ppmt(rate, per, nper, pv [, fv [, type]])
+	registry.registerTypedFunction("ppmt", func(arguments []float64) (float64, error) {
+		if err := checkPeriod(arguments[1], arguments[2]); err != nil {
+			return 0, err
+		}
+		rate, period, periods, presentValue := arguments[0], arguments[1], arguments[2], arguments[3]
+		futureValue, when := optionalArgument(arguments, 4, 0), paymentType(arguments, 5)
+		return annuityPayment(rate, periods, presentValue, futureValue, when) -
+			interestPayment(rate, period, periods, presentValue, futureValue, when), nil
+	}, 4, 6, false, true)
+
+	// fv(rate, nper, pmt [, pv [, type]])
+	registry.registerTypedFunction("fv", func(arguments []float64) (float64, error) {
+		return -compoundedValue(arguments[0], arguments[1], arguments[2], optionalArgument(arguments, 3, 0), paymentType(arguments, 4)), nil
+	}, 3, 5, false, true)
+
+	// pv(rate, nper, pmt [, fv [, type]])
+	registry.registerTypedFunction("pv", func(arguments []float64) (float64, error) {
+		rate, periods, payment := arguments[0], arguments[1], arguments[2]
+		futureValue, when := optionalArgument(arguments, 3, 0), paymentType(arguments, 4)
+
+		if rate == 0 {
+			return -(futureValue + payment*periods), nil
+		}
+		growth := math.Pow(1+rate, periods)
+		return -(futureValue + payment*(1+rate*when)*(growth-1)/rate) / growth, nil
+	}, 3, 5, false, true)
+
+	// nper(rate, pmt, pv [, fv [, type]])
+	registry.registerTypedFunction("nper", func(arguments []float64) (float64, error) {
+		rate, payment, presentValue := arguments[0], arguments[1], arguments[2]
+		futureValue, when := optionalArgument(arguments, 3, 0), paymentType(arguments, 4)
+
+		if rate == 0 {
+			if payment == 0 {
+				return 0, errors.New("the payment cannot be zero when the rate is zero")
+			}
+			return -(presentValue + futureValue) / payment, nil
+		}
+		adjustedPayment := payment * (1 + rate*when)
+		return math.Log((adjustedPayment-futureValue*rate)/(adjustedPayment+presentValue*rate)) / math.Log(1+rate), nil
+	}, 3, 5, false, true)
+
+	// rate(nper, pmt, pv [, fv [, type [, guess]]])
+	registry.registerTypedFunction("rate", func(arguments []float64) (float64, error) {
+		periods, payment, presentValue := arguments[0], arguments[1], arguments[2]
+		futureValue, when := optionalArgument(arguments, 3, 0), paymentType(arguments, 4)
+
+		return solver.solve(func(rate float64) float64 {
+			return compoundedValue(rate, periods, payment, presentValue, when) + futureValue
+		}, optionalArgument(arguments, 5, 0.1))
+	}, 3, 6, false, true)
+
+	// npv(rate, v1, ..., vn), the values are at the end of the periods 1 to n
+	registry.registerTypedFunction("npv", func(arguments []float64) (float64, error) {
+		if err := requireValues(arguments[1:], 1); err != nil {
+			return 0, err
+		}
+		return netPresentValue(arguments[0], arguments[1:], 1), nil
+	}, 1, unlimitedParameters, false, true)
+
+	// irr(v1, ..., vn), the values are at the start of the periods 0 to n-1
+	registry.registerTypedFunction("irr", func(arguments []float64) (float64, error) {
+		if err := requireValues(arguments, 2); err != nil {
+			return 0, err
+		}
+		if err := checkCashFlows(arguments); err != nil {
+			return 0, err
+		}
+		return solver.solve(func(rate float64) float64 {
+			return netPresentValue(rate, arguments, 0)
+		}, 0.1)
+	}, 0, unlimitedParameters, false, true)
+
+	// xnpv(rate, v1, ..., vn, d1, ..., dn), the value 'vi' is paid at the date 'di'
+	registry.registerValueFunction("xnpv", withNullArguments(func(arguments []Value) (Value, error) {
+		rate, ok := arguments[0].Float64()
+		if !ok {
+			return nullValue, fmt.Errorf("the argument 1 is a %s, expected a number", arguments[0].kind)
+		}
+
+		count := (len(arguments) - 1) / 2
+		if (len(arguments)-1)%2 != 0 {
+			return nullValue, fmt.Errorf("expected as many dates as values, got %d arguments after the rate", len(arguments)-1)
+		}
+		if count == 0 {
+			return nullValue, fmt.Errorf("%w: expected at least 1, got 0", ErrNotEnoughValues)
+		}
+
+		start, err := dateArgument(arguments, 1+count)
+		if err != nil {
+			return nullValue, err
+		}
+
+		sum := 0.0
+		for idx := 1; idx <= count; idx++ {
+			value, ok := arguments[idx].Float64()
+			if !ok {
+				return nullValue, fmt.Errorf("the argument %d is a %s, expected a number", idx+1, arguments[idx].kind)
+			}
+			date, err := dateArgument(arguments, idx+count)
+			if err != nil {
+				return nullValue, err
+			}
+			years := float64(date.Sub(start)) / float64(365*24*time.Hour)
+			sum += value / math.Pow(1+rate, years)
+		}
+		return NumberValue(sum), nil
+	}), 1, unlimitedParameters, false, true)
+
+	// sln(cost, salvage, life)
+	registry.registerTypedFunction("sln", func(arguments []float64) (float64, error) {
+		if arguments[2] == 0 {
+			return 0, errors.New("the life cannot be zero")
+		}
+		return (arguments[0] - arguments[1]) / arguments[2], nil
+	}, 3, 3, false, true)
+
+	// db(cost, salvage, life, period [, month])
+	registry.registerTypedFunction("db", func(arguments []float64) (float64, error) {
+		cost, salvage, life, period := arguments[0], arguments[1], arguments[2], arguments[3]
+		month := optionalArgument(arguments, 4, 12)
+
+		if cost <= 0 || salvage < 0 || life <= 0 || month < 1 || month > 12 {
+			return 0, errors.New("expected a positive cost and life, a salvage of zero or more and a month between 1 and 12")
+		}
+		if period < 1 || period > life+1 || (period > life && month == 12) || period != math.Trunc(period) {
+			return 0, fmt.Errorf("the period must be an integer between 1 and %v, got %v", life, period)
+		}
+
+		// the rate is rounded to three decimal places, like Excel does
+		rate := math.Round((1-math.Pow(salvage/cost, 1/life))*1000) / 1000
+
+		depreciation := cost * rate * month / 12
+		total := depreciation
+		for current := 2.0; current <= period; current++ {
+			if current > life {
+				// the months of the first year that were not depreciated
+				depreciation = (cost - total) * rate * (12 - month) / 12
+			} else {
+				depreciation = (cost - total) * rate
+			}
+			total += depreciation
+		}
+		return depreciation, nil
+	}, 4, 5, false, true)
+}
+
+func optionalArgument(arguments []float64, idx int, defaultValue float64) float64 {
+	if idx < len(arguments) {
+		return arguments[idx]
+	}
+	return defaultValue
+}
+
+// Returns 1 when the payments are due at the beginning of the periods, 0 otherwise.
+func paymentType(arguments []float64, idx int) float64 {
+	if optionalArgument(arguments, idx, 0) != 0 {
+		return 1
+	}
+	return 0
+}
+
+func checkPeriod(period float64, periods float64) error {
+	if period < 1 || period > periods {
+		return fmt.Errorf("the period must be between 1 and %v, got %v", periods, period)
+	}
+	return nil
+}
+
+func checkCashFlows(values []float64) error {
+	hasPositive, hasNegative := false, false
+	for _, value := range values {
+		hasPositive = hasPositive || value > 0
+		hasNegative = hasNegative || value < 0
+	}
+	if !hasPositive || !hasNegative {
+		return errors.New("expected at least one positive and one negative value")
+	}
+	return nil
+}
+
+func annuityPayment(rate float64, periods float64, presentValue float64, futureValue float64, when float64) float64 {
+	if rate == 0 {
+		return -(presentValue + futureValue) / periods
+	}
+	growth := math.Pow(1+rate, periods)
+	return -rate * (futureValue + presentValue*growth) / ((1 + rate*when) * (growth - 1))
+}
+
+// The future value of the present value and of the payments, with the sign of the present value.
+func compoundedValue(rate float64, periods float64, payment float64, presentValue float64, when float64) float64 {
+	if rate == 0 {
+		return presentValue + payment*periods
+	}
+	growth := math.Pow(1+rate, periods)
+	return presentValue*growth + payment*(1+rate*when)*(growth-1)/rate
+}
+
+func interestPayment(rate float64, period float64, periods float64, presentValue float64, futureValue float64, when float64) float64 {
+	fixedPayment := annuityPayment(rate, periods, presentValue, futureValue, when)
+
+	var balance float64
+	switch {
+	case period == 1 && when == 1:
+		// the first payment is made before any interest is due
+		return 0
+	case period == 1:
+		balance = -presentValue
+	case when == 1:
+		balance = -compoundedValue(rate, period-2, fixedPayment, presentValue, 1) - fixedPayment
+	default:
+		balance = -compoundedValue(rate, period-1, fixedPayment, presentValue, 0)
+	}
+	return balance * rate
+}
+
+// Discounts the values, the first one being at the period [firstPeriod].
+func netPresentValue(rate float64, values []float64, firstPeriod int) float64 {
+	sum := 0.0
+	for idx, value := range values {
+		sum += value / math.Pow(1+rate, float64(idx+firstPeriod))
+	}
+	return sum
+}
+
+/*
+	Finds, with the Newton's method, the rate that makes [f] zero starting from [guess].
+	Returns ErrNoConvergence when the rate does not change by less than the tolerance within the
+	maximum number of iterations.
+*/
+func (this solverOptions) solve(f func(rate float64) float64, guess float64) (float64, error) {
+	rate := guess
+	for iteration := 0; iteration < this.maxIterations; iteration++ {
+		value := f(rate)
+		step := 1e-6 * math.Max(1, math.Abs(rate))
+		derivative := (f(rate+step) - value) / step
+
+		if derivative == 0 || math.IsNaN(derivative) || math.IsInf(derivative, 0) {
+			break
+		}
+
+		next := rate - value/derivative
+		if math.IsNaN(next) || math.IsInf(next, 0) || next <= -1 {
+			break
+		}
+		if math.Abs(next-rate) < this.tolerance {
+			return next, nil
+		}
+		rate = next
+	}
+	return 0, fmt.Errorf("%w after %d iterations", ErrNoConvergence, this.maxIterations)
+}
@@ -1,23 +1,64 @@
 package gojacego
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"math"
-	"math/rand"
 	"strings"
 )
 
 type Delegate func(arguments ...interface{}) float64
 
+/*
+	TypedDelegate is a function that receives its arguments as float64 values and can signal
+	a failure by returning an error. The error is reported by the formula together with the name
+	of the function and the position of the call.
+*/
+type TypedDelegate func(arguments []float64) (float64, error)
+
+/*
+	ContextDelegate is a TypedDelegate that also receives the context of the evaluation, which
+	carries request-scoped data, deadlines and cancellation.
+*/
+type ContextDelegate func(ctx context.Context, arguments []float64) (float64, error)
+
+/*
+	A function whose first argument can be a variable that is not defined: instead of failing, the
+	function is told whether it is defined. The value of an undefined variable is zero.
+*/
+type variableDelegate func(isDefined bool, arguments []Value) (Value, error)
+
+// A function that receives its arguments as values, so it can handle the null ones.
+type valueDelegate func(arguments []Value) (Value, error)
+
+/*
+	A function that evaluates its arguments only when it needs them, i.e. to recover from the errors
+	of an argument.
+*/
+type lazyDelegate func(arguments []lazyArgument) (Value, error)
+
+const unlimitedParameters = -1
+
 type functionRegistry struct {
 	caseSensitive bool
 	functions     map[string]functionInfo
 }
 
 type functionInfo struct {
-	name           string
-	function       Delegate
-	isOverWritable bool
-	isIdempotent   bool
+	name             string
+	function         Delegate
+	typedFunction    TypedDelegate
+	contextFunction  ContextDelegate
+	variableFunction variableDelegate
+	valueFunction    valueDelegate
+	lazyFunction     lazyDelegate
+	minParameters    int
+	maxParameters    int
+	isOverWritable   bool
+	isIdempotent     bool
+	// the function returns integers
+	isInteger bool
 }
 
 func newFunctionRegistry(caseSensitive bool) *functionRegistry {
@@ -35,7 +76,73 @@ func (this *functionRegistry) get(name string) (*functionInfo, bool) {
 }
 
 func (this *functionRegistry) registerFunction(name string, function Delegate, isOverWritable bool, isIdempotent bool) {
-	handledFunctionName := this.convertFunctionName(name)
+	this.register(functionInfo{
+		name:           name,
+		function:       function,
+		minParameters:  0,
+		maxParameters:  unlimitedParameters,
+		isOverWritable: isOverWritable,
+		isIdempotent:   isIdempotent,
+	})
+}
+
+func (this *functionRegistry) registerTypedFunction(name string, function TypedDelegate, minParameters int, maxParameters int, isOverWritable bool, isIdempotent bool) {
+	this.register(functionInfo{
+		name:           name,
+		typedFunction:  function,
+		minParameters:  minParameters,
+		maxParameters:  maxParameters,
+		isOverWritable: isOverWritable,
+		isIdempotent:   isIdempotent,
+	})
+}
+
+func (this *functionRegistry) registerContextFunction(name string, function ContextDelegate, minParameters int, maxParameters int, isOverWritable bool, isIdempotent bool) {
+	this.register(functionInfo{
+		name:            name,
+		contextFunction: function,
+		minParameters:   minParameters,
+		maxParameters:   maxParameters,
+		isOverWritable:  isOverWritable,
+		isIdempotent:    isIdempotent,
+	})
+}
+
+func (this *functionRegistry) registerVariableFunction(name string, function variableDelegate, minParameters int, maxParameters int, isOverWritable bool, isIdempotent bool) {
+	this.register(functionInfo{
+		name:             name,
+		variableFunction: function,
+		minParameters:    minParameters,
+		maxParameters:    maxParameters,
+		isOverWritable:   isOverWritable,
+		isIdempotent:     isIdempotent,
+	})
+}
+
+func (this *functionRegistry) registerValueFunction(name string, function valueDelegate, minParameters int, maxParameters int, isOverWritable bool, isIdempotent bool) {
+	this.register(functionInfo{
+		name:           name,
+		valueFunction:  function,
+		minParameters:  minParameters,
+		maxParameters:  maxParameters,
+		isOverWritable: isOverWritable,
+		isIdempotent:   isIdempotent,
+	})
+}
+
+func (this *functionRegistry) registerLazyFunction(name string, function lazyDelegate, minParameters int, maxParameters int, isOverWritable bool, isIdempotent bool) {
+	this.register(functionInfo{
+		name:           name,
+		lazyFunction:   function,
+		minParameters:  minParameters,
+		maxParameters:  maxParameters,
+		isOverWritable: isOverWritable,
+		isIdempotent:   isIdempotent,
+	})
+}
+
+func (this *functionRegistry) register(info functionInfo) {
+	handledFunctionName := this.convertFunctionName(info.name)
 
 	if item, found := this.functions[handledFunctionName]; found {
 		if !item.isOverWritable {
@@ -43,14 +150,8 @@ func (this *functionRegistry) registerFunction(name string, function Delegate, i
 		}
 	}
 
-	functionInfo := &functionInfo{
-		name:           handledFunctionName,
-		function:       function,
-		isOverWritable: isOverWritable,
-		isIdempotent:   isIdempotent,
-	}
-
-	this.functions[handledFunctionName] = *functionInfo
+	info.name = handledFunctionName
+	this.functions[handledFunctionName] = info
 }
 
 func (this *functionRegistry) convertFunctionName(name string) string {
@@ -60,105 +161,240 @@ func (this *functionRegistry) convertFunctionName(name string) string {
 	return strings.ToLower(name)
 }
 
-func registryDefaultFunctions(registry *functionRegistry) {
-
-	registry.registerFunction("sin", func(arguments ...interface{}) float64 {
-		return math.Sin(arguments[0].(float64))
-	}, false, true)
-
-	registry.registerFunction("cos", func(arguments ...interface{}) float64 {
-		return math.Cos(arguments[0].(float64))
-	}, false, true)
+// Checks whether the function accepts the given number of arguments.
+func (this *functionInfo) validateNumberOfParameters(numberOfParameters int) error {
+	if numberOfParameters >= this.minParameters && (this.maxParameters == unlimitedParameters || numberOfParameters <= this.maxParameters) {
+		return nil
+	}
 
-	registry.registerFunction("asin", func(arguments ...interface{}) float64 {
-		return math.Asin(arguments[0].(float64))
-	}, false, true)
+	switch {
+	case this.minParameters == this.maxParameters:
+		return fmt.Errorf("expected %d argument(s), got %d", this.minParameters, numberOfParameters)
+	case this.maxParameters == unlimitedParameters:
+		return fmt.Errorf("expected at least %d argument(s), got %d", this.minParameters, numberOfParameters)
+	default:
+		return fmt.Errorf("expected between %d and %d arguments, got %d", this.minParameters, this.maxParameters, numberOfParameters)
+	}
+}
 
-	registry.registerFunction("acos", func(arguments ...interface{}) float64 {
-		return math.Acos(arguments[0].(float64))
-	}, false, true)
+/*
+	Returns the logarithm of [x] in the base [base], with the special cases of .NET's 'Math.Log(a, newBase)'.
+*/
+func logarithm(x float64, base float64) float64 {
+	switch {
+	case math.IsNaN(x):
+		return x
+	case math.IsNaN(base):
+		return base
+	case base == 1:
+		return math.NaN()
+	case x != 1 && (base == 0 || math.IsInf(base, 1)):
+		return math.NaN()
+	}
+	return math.Log(x) / math.Log(base)
+}
 
-	registry.registerFunction("tan", func(arguments ...interface{}) float64 {
-		return math.Tan(arguments[0].(float64))
-	}, false, true)
+func registryDefaultFunctions(registry *functionRegistry, options *jaceOptions) {
 
-	registry.registerFunction("atan", func(arguments ...interface{}) float64 {
-		return math.Atan(arguments[0].(float64))
-	}, false, true)
+	registryTrigonometricFunctions(registry, options.angleUnit)
 
-	registry.registerFunction("log", func(arguments ...interface{}) float64 {
-		return math.Log(arguments[0].(float64))
-	}, false, true)
+	registry.registerTypedFunction("log", func(arguments []float64) (float64, error) {
+		return math.Log(arguments[0]), nil
+	}, 1, 1, false, true)
 
-	registry.registerFunction("sqrt", func(arguments ...interface{}) float64 {
-		return math.Sqrt(arguments[0].(float64))
-	}, false, true)
+	registry.registerTypedFunction("sqrt", func(arguments []float64) (float64, error) {
+		return math.Sqrt(arguments[0]), nil
+	}, 1, 1, false, true)
 
-	registry.registerFunction("trunc", func(arguments ...interface{}) float64 {
-		return math.Trunc(arguments[0].(float64))
-	}, false, true)
+	registry.registerTypedFunction("trunc", func(arguments []float64) (float64, error) {
+		return math.Trunc(arguments[0]), nil
+	}, 1, 1, false, true)
 
-	registry.registerFunction("ceil", func(arguments ...interface{}) float64 {
-		return math.Ceil(arguments[0].(float64))
-	}, false, true)
+	registry.registerTypedFunction("ceil", func(arguments []float64) (float64, error) {
+		return math.Ceil(arguments[0]), nil
+	}, 1, 1, false, true)
 
-	registry.registerFunction("round", func(arguments ...interface{}) float64 {
+	registry.registerTypedFunction("round", func(arguments []float64) (float64, error) {
 		if len(arguments) <= 1 {
-			return math.Round(arguments[0].(float64))
+			return math.Round(arguments[0]), nil
 		} else {
-			pow := math.Pow(10, arguments[1].(float64))
-			return math.Round(arguments[0].(float64)*pow) / pow
+			pow := math.Pow(10, arguments[1])
+			return math.Round(arguments[0]*pow) / pow, nil
 		}
-	}, false, true)
-
-	registry.registerFunction("random", func(arguments ...interface{}) float64 {
-		rand.Seed(int64(arguments[0].(float64)))
-		return rand.Float64()
-	}, false, false)
+	}, 1, 2, false, true)
 
-	registry.registerFunction("floor", func(arguments ...interface{}) float64 {
-		return math.Floor(arguments[0].(float64))
-	}, false, true)
+	registry.registerTypedFunction("floor", func(arguments []float64) (float64, error) {
+		return math.Floor(arguments[0]), nil
+	}, 1, 1, false, true)
 
-	registry.registerFunction("max", func(arguments ...interface{}) float64 {
+	registry.registerTypedFunction("max", func(arguments []float64) (float64, error) {
 		if len(arguments) > 0 {
-			max := arguments[0].(float64)
+			max := arguments[0]
 			for _, v := range arguments {
-				if v.(float64) > max {
-					max = v.(float64)
+				if v > max {
+					max = v
 				}
 			}
-			return max
+			return max, nil
 		} else {
-			return 0
+			return 0, nil
 		}
-	}, false, true)
+	}, 0, unlimitedParameters, false, true)
 
-	registry.registerFunction("min", func(arguments ...interface{}) float64 {
+	registry.registerTypedFunction("min", func(arguments []float64) (float64, error) {
 		if len(arguments) > 0 {
-			min := arguments[0].(float64)
+			min := arguments[0]
 			for _, v := range arguments {
-				if v.(float64) < min {
-					min = v.(float64)
+				if v < min {
+					min = v
 				}
 			}
-			return min
+			return min, nil
 		} else {
-			return 0
+			return 0, nil
 		}
-	}, false, true)
-
-	registry.registerFunction("if", func(arguments ...interface{}) float64 {
-		if len(arguments) == 3 {
-			if arguments[0].(float64) != 0.0 {
-				return arguments[1].(float64)
-			} else {
-				return arguments[2].(float64)
-			}
+	}, 0, unlimitedParameters, false, true)
+
+	// the functions below were added after the others, they can be overwritten so the custom
+	// functions of the same name keep working
+	registry.registerTypedFunction("sum", func(arguments []float64) (float64, error) {
+		sum := 0.0
+		for _, v := range arguments {
+			sum += v
+		}
+		return sum, nil
+	}, 0, unlimitedParameters, true, true)
+
+	registry.registerTypedFunction("avg", averageFunction, 0, unlimitedParameters, true, true)
+	registry.registerTypedFunction("median", medianFunction, 0, unlimitedParameters, true, true)
+
+	registry.registerTypedFunction("abs", func(arguments []float64) (float64, error) {
+		return math.Abs(arguments[0]), nil
+	}, 1, 1, true, true)
+
+	registry.registerTypedFunction("sign", func(arguments []float64) (float64, error) {
+		switch {
+		case math.IsNaN(arguments[0]):
+			return 0, errors.New("the sign of NaN is undefined")
+		case arguments[0] > 0:
+			return 1, nil
+		case arguments[0] < 0:
+			return -1, nil
+		}
+		return 0, nil
+	}, 1, 1, true, true)
+
+	registry.registerTypedFunction("exp", func(arguments []float64) (float64, error) {
+		return math.Exp(arguments[0]), nil
+	}, 1, 1, true, true)
+
+	registry.registerTypedFunction("loge", func(arguments []float64) (float64, error) {
+		return math.Log(arguments[0]), nil
+	}, 1, 1, true, true)
+
+	registry.registerTypedFunction("log10", func(arguments []float64) (float64, error) {
+		return math.Log10(arguments[0]), nil
+	}, 1, 1, true, true)
+
+	registry.registerTypedFunction("logn", func(arguments []float64) (float64, error) {
+		return logarithm(arguments[0], arguments[1]), nil
+	}, 2, 2, true, true)
+
+	registry.registerTypedFunction("ceiling", func(arguments []float64) (float64, error) {
+		return math.Ceil(arguments[0]), nil
+	}, 1, 1, true, true)
+
+	registry.registerTypedFunction("truncate", func(arguments []float64) (float64, error) {
+		return math.Trunc(arguments[0]), nil
+	}, 1, 1, true, true)
+
+	registry.registerTypedFunction("ifless", func(arguments []float64) (float64, error) {
+		if arguments[0] < arguments[1] {
+			return arguments[2], nil
+		}
+		return arguments[3], nil
+	}, 4, 4, true, true)
 
+	registry.registerTypedFunction("ifmore", func(arguments []float64) (float64, error) {
+		if arguments[0] > arguments[1] {
+			return arguments[2], nil
+		}
+		return arguments[3], nil
+	}, 4, 4, true, true)
+
+	registry.registerTypedFunction("ifequal", func(arguments []float64) (float64, error) {
+		if arguments[0] == arguments[1] {
+			return arguments[2], nil
+		}
+		return arguments[3], nil
+	}, 4, 4, true, true)
+
+	// the hyperbolic functions, defined like in Jace.NET
+	for name, function := range map[string]func(float64) float64{
+		"sinh":  math.Sinh,
+		"cosh":  math.Cosh,
+		"tanh":  math.Tanh,
+		"coth":  func(x float64) float64 { return 1 / math.Tanh(x) },
+		"sech":  func(x float64) float64 { return 1 / math.Cosh(x) },
+		"csch":  func(x float64) float64 { return 1 / math.Sinh(x) },
+		"asinh": math.Asinh,
+		"acosh": math.Acosh,
+		"atanh": math.Atanh,
+		"acoth": func(x float64) float64 { return math.Atanh(1 / x) },
+		"asech": func(x float64) float64 { return math.Acosh(1 / x) },
+		"acsch": func(x float64) float64 { return math.Asinh(1 / x) },
+	} {
+		function := function
+		registry.registerTypedFunction(name, func(arguments []float64) (float64, error) {
+			return function(arguments[0]), nil
+		}, 1, 1, true, true)
+	}
+
+	registry.registerValueFunction("if", func(arguments []Value) (Value, error) {
+		if kind := arguments[0].Kind(); kind != KindNumber && kind != KindNull {
+			return nullValue, fmt.Errorf("the condition is a %s, expected a number", kind)
+		}
+
+		// a null condition is not true
+		if isTrue(arguments[0]) {
+			return arguments[1], nil
 		} else {
-			return 0
+			return arguments[2], nil
+		}
+	}, 3, 3, false, true)
+
+	registry.registerVariableFunction("isdefined", func(isDefined bool, arguments []Value) (Value, error) {
+		return booleanValue(isDefined), nil
+	}, 1, 1, true, true)
+
+	registry.registerVariableFunction("default", func(isDefined bool, arguments []Value) (Value, error) {
+		if isDefined {
+			return arguments[0], nil
 		}
-	}, false, true)
+		return arguments[1], nil
+	}, 2, 2, true, true)
+
+	registry.registerValueFunction("isnull", func(arguments []Value) (Value, error) {
+		return booleanValue(arguments[0].IsNull()), nil
+	}, 1, 1, true, true)
+
+	registry.registerValueFunction("coalesce", func(arguments []Value) (Value, error) {
+		for _, argument := range arguments {
+			if !argument.IsNull() {
+				return argument, nil
+			}
+		}
+		return nullValue, nil
+	}, 1, unlimitedParameters, true, true)
+
+	registry.registerValueFunction("nullif", func(arguments []Value) (Value, error) {
+		if !arguments[0].IsNull() && arguments[0].equals(arguments[1]) {
+			return nullValue, nil
+		}
+		return arguments[0], nil
+	}, 2, 2, true, true)
+
+	registryDateFunctions(registry)
+	registryRandomFunctions(registry, options)
 
 }
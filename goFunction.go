@@ -0,0 +1,182 @@
+package gojacego
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+)
+
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
+
+/*
+	Represents an arbitrary Go function that can be called from a formula. The signature is
+	inspected once, so the arguments can be converted from the engine's values on every call.
+*/
+type goFunction struct {
+	function     reflect.Value
+	parameters   []reflect.Type
+	isVariadic   bool
+	returnsError bool
+}
+
+func newGoFunction(function interface{}) (*goFunction, error) {
+
+	if function == nil {
+		return nil, errors.New("the function cannot be nil")
+	}
+
+	value := reflect.ValueOf(function)
+	signature := value.Type()
+
+	if signature.Kind() != reflect.Func {
+		return nil, fmt.Errorf("expected a function, got %s", signature)
+	}
+
+	if value.IsNil() {
+		return nil, errors.New("the function cannot be nil")
+	}
+
+	parameters := make([]reflect.Type, signature.NumIn())
+	for i := 0; i < signature.NumIn(); i++ {
+		parameter := signature.In(i)
+		if signature.IsVariadic() && i == signature.NumIn()-1 {
+			parameter = parameter.Elem()
+		}
+
+		if !isSupportedGoParameter(parameter) {
+			return nil, fmt.Errorf("unsupported parameter type %s in %s", parameter, signature)
+		}
+		parameters[i] = parameter
+	}
+
+	returnsError := false
+	switch signature.NumOut() {
+	case 1:
+	case 2:
+		if signature.Out(1) != errorType {
+			return nil, fmt.Errorf("the second result of %s must be an error", signature)
+		}
+		returnsError = true
+	default:
+		return nil, fmt.Errorf("%s must return a number and, optionally, an error", signature)
+	}
+
+	if !isSupportedGoResult(signature.Out(0)) {
+		return nil, fmt.Errorf("unsupported result type %s in %s", signature.Out(0), signature)
+	}
+
+	return &goFunction{
+		function:     value,
+		parameters:   parameters,
+		isVariadic:   signature.IsVariadic(),
+		returnsError: returnsError,
+	}, nil
+}
+
+func (this *goFunction) minParameters() int {
+	if this.isVariadic {
+		return len(this.parameters) - 1
+	}
+	return len(this.parameters)
+}
+
+func (this *goFunction) maxParameters() int {
+	if this.isVariadic {
+		return unlimitedParameters
+	}
+	return len(this.parameters)
+}
+
+func (this *goFunction) call(arguments []Value) (Value, error) {
+
+	in := make([]reflect.Value, len(arguments))
+	for idx, argument := range arguments {
+		parameter := this.parameters[len(this.parameters)-1]
+		if idx < len(this.parameters) {
+			parameter = this.parameters[idx]
+		}
+
+		converted, err := convertGoArgument(argument, parameter)
+		if err != nil {
+			return nullValue, fmt.Errorf("argument %d: %s", idx+1, err.Error())
+		}
+		in[idx] = converted
+	}
+
+	out := this.function.Call(in)
+
+	if this.returnsError && !out[1].IsNil() {
+		return nullValue, out[1].Interface().(error)
+	}
+
+	return NumberValue(convertGoResult(out[0])), nil
+}
+
+func isSupportedGoParameter(parameter reflect.Type) bool {
+	switch parameter.Kind() {
+	case reflect.Float32, reflect.Float64,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Bool, reflect.String:
+		return true
+	}
+	return false
+}
+
+func isSupportedGoResult(result reflect.Type) bool {
+	return result.Kind() != reflect.String && isSupportedGoParameter(result)
+}
+
+func convertGoArgument(argumentValue Value, parameter reflect.Type) (reflect.Value, error) {
+	value := reflect.New(parameter).Elem()
+
+	// the strings are only given to the string parameters, which also accept the numbers
+	if text, ok := argumentValue.Text(); ok && parameter.Kind() == reflect.String {
+		value.SetString(text)
+		return value, nil
+	}
+
+	if argumentValue.kind != KindNumber {
+		return value, fmt.Errorf("a %s cannot be converted to %s", argumentValue.kind, parameter)
+	}
+
+	argument := argumentValue.number
+
+	switch parameter.Kind() {
+	case reflect.Float32, reflect.Float64:
+		value.SetFloat(argument)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if argument != math.Trunc(argument) || argument < math.MinInt64 || argument >= math.MaxInt64 || value.OverflowInt(int64(argument)) {
+			return value, fmt.Errorf("%v cannot be converted to %s", argument, parameter)
+		}
+		value.SetInt(int64(argument))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if argument != math.Trunc(argument) || argument < 0 || argument >= math.MaxUint64 || value.OverflowUint(uint64(argument)) {
+			return value, fmt.Errorf("%v cannot be converted to %s", argument, parameter)
+		}
+		value.SetUint(uint64(argument))
+	case reflect.Bool:
+		value.SetBool(argument != 0.0)
+	case reflect.String:
+		value.SetString(strconv.FormatFloat(argument, 'f', -1, 64))
+	}
+
+	return value, nil
+}
+
+func convertGoResult(result reflect.Value) float64 {
+	switch result.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(result.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(result.Uint())
+	case reflect.Bool:
+		if result.Bool() {
+			return 1.0
+		}
+		return 0.0
+	}
+	return result.Float()
+}
@@ -0,0 +1,175 @@
+package gojacego
+
+import (
+	"errors"
+	"math"
+	"strings"
+	"testing"
+)
+
+type cents int64
+
+func TestGoFunctions(test *testing.T) {
+	engine, _ := NewCalculationEngine()
+
+	engine.AddGoFunc("clamp", func(x, lo, hi float64) float64 {
+		return math.Max(lo, math.Min(x, hi))
+	})
+
+	engine.AddGoFunc("total", func(first float32, others ...float64) float64 {
+		total := float64(first)
+		for _, v := range others {
+			total += v
+		}
+		return total
+	})
+
+	engine.AddGoFunc("shift", func(value int, bits uint8) int {
+		return value << bits
+	})
+
+	engine.AddGoFunc("negate", func(value bool) bool {
+		return !value
+	})
+
+	engine.AddGoFunc("digits", func(value string) int {
+		return len(value)
+	})
+
+	engine.AddGoFunc("dollars", func(value cents) float64 {
+		return float64(value) / 100
+	})
+
+	scenarios := []CalculationTestScenario{
+		{
+			formula:        "clamp(15, 0, 10)",
+			expectedResult: 10,
+		},
+		{
+			formula:        "clamp(x, 0, 10)",
+			expectedResult: 2.5,
+			variables: map[string]interface{}{
+				"x": 2.5,
+			},
+		},
+		{
+			formula:        "total(1)",
+			expectedResult: 1,
+		},
+		{
+			formula:        "total(1, 2, 3.5)",
+			expectedResult: 6.5,
+		},
+		{
+			formula:        "shift(1, 4)",
+			expectedResult: 16,
+		},
+		{
+			formula:        "negate(0)",
+			expectedResult: 1,
+		},
+		{
+			formula:        "digits(12.25)",
+			expectedResult: 5,
+		},
+		{
+			formula:        `digits("abc")`,
+			expectedResult: 3,
+		},
+		{
+			formula:        "dollars(1250)",
+			expectedResult: 12.5,
+		},
+	}
+
+	runScenarios(engine, runCalculate, scenarios, test)
+}
+
+func TestGoFunctionError(test *testing.T) {
+	engine, _ := NewCalculationEngine()
+
+	errInvalid := errors.New("invalid input")
+
+	engine.AddGoFunc("check", func(value float64) (float64, error) {
+		if value < 0 {
+			return 0, errInvalid
+		}
+		return value, nil
+	})
+
+	result, err := engine.Calculate("check(2)", nil)
+	if err != nil || result != 2 {
+		test.Errorf("expected: 2, got: %f (%v)", result, err)
+	}
+
+	_, err = engine.Calculate("check(-2)", nil)
+	if !errors.Is(err, errInvalid) {
+		test.Errorf("expected: %v, got: %v", errInvalid, err)
+	}
+}
+
+func TestGoFunctionArguments(test *testing.T) {
+	engine, _ := NewCalculationEngine()
+
+	engine.AddGoFunc("half", func(value int) int {
+		return value / 2
+	})
+
+	engine.AddGoFunc("small", func(value uint8) uint8 {
+		return value
+	})
+
+	scenarios := []string{"half(2.5)", "half(1, 2)", "half()", "small(-1)", "small(256)", `half("2")`}
+
+	for _, formula := range scenarios {
+		_, err := engine.Calculate(formula, nil)
+
+		var fnErr *FunctionError
+		if !errors.As(err, &fnErr) {
+			test.Errorf("%s => expected: *FunctionError, got: %v", formula, err)
+		}
+	}
+}
+
+func TestGoFunctionUnsupportedSignatures(test *testing.T) {
+	engine, _ := NewCalculationEngine()
+
+	var nilFunction func(float64) float64
+
+	scenarios := []interface{}{
+		nil,
+		42,
+		nilFunction,
+		func() {},
+		func(float64) string { return "" },
+		func(float64) (float64, float64) { return 0, 0 },
+		func(float64) (float64, error, error) { return 0, nil, nil },
+		func([]float64) float64 { return 0 },
+		func(map[string]float64) float64 { return 0 },
+		func(...interface{}) float64 { return 0 },
+	}
+
+	for _, function := range scenarios {
+		err := engine.AddGoFunc("unsupported", function)
+		if err == nil || !strings.Contains(err.Error(), "unsupported") {
+			test.Errorf("%T => expected an error, got: %v", function, err)
+		}
+	}
+
+	if _, found := engine.functionRegistry.get("unsupported"); found {
+		test.Errorf("unsupported function should not be registered")
+	}
+}
+
+func TestAddGoFuncNotOverwritable(test *testing.T) {
+	engine, _ := NewCalculationEngine()
+
+	err := engine.AddGoFunc("SIN", func(x float64) float64 { return x })
+	if err == nil || !strings.Contains(err.Error(), "cannot be overwritten") {
+		test.Errorf("expected an error, got: %v", err)
+	}
+
+	if result, err := engine.Calculate("sin(0)", nil); err != nil || result != 0 {
+		test.Errorf("expected: 0, got: %v (%v)", result, err)
+	}
+}
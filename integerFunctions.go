@@ -0,0 +1,192 @@
+package gojacego
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+)
+
+/*
+	ErrIntegerOverflow is returned, wrapped in a *FunctionError, by the integer functions whose result
+	is larger than 2^53, the largest integer that a float64 holds exactly.
+*/
+var ErrIntegerOverflow = errors.New("integer overflow")
+
+// A function computing exactly on integers, whose arguments and result are held exactly by a float64.
+type integerDelegate func(arguments []int64) (int64, error)
+
+// 2^53, the largest integer that a float64 holds exactly (along with all the smaller ones).
+const maxExactInteger = 1 << 53
+
+/*
+	Registers the functions of the IntegerPack. Its exact 'fact' replaces the float one of the
+	SpecialMathPack when both packs are enabled, as the packs are registered in their order.
+*/
+func registryIntegerFunctions(registry *functionRegistry, options *jaceOptions) {
+
+	registry.registerIntegerFunction("fact", factorialFunction, 1, 1, true)
+
+	// ncr(n, k), the number of combinations of k items among n
+	registry.registerIntegerFunction("ncr", func(arguments []int64) (int64, error) {
+		n, k := arguments[0], arguments[1]
+		if n < 0 || k < 0 || k > n {
+			return 0, domainError("expected 0 <= k <= n, got n = %d and k = %d", n, k)
+		}
+		return exactBigInteger(new(big.Int).Binomial(n, k))
+	}, 2, 2, false)
+
+	// npr(n, k), the number of arrangements of k items among n
+	registry.registerIntegerFunction("npr", func(arguments []int64) (int64, error) {
+		n, k := arguments[0], arguments[1]
+		if n < 0 || k < 0 || k > n {
+			return 0, domainError("expected 0 <= k <= n, got n = %d and k = %d", n, k)
+		}
+		return exactBigInteger(new(big.Int).MulRange(n-k+1, n))
+	}, 2, 2, false)
+
+	registry.registerIntegerFunction("gcd", func(arguments []int64) (int64, error) {
+		result := int64(0)
+		for _, argument := range arguments {
+			result = gcd(result, argument)
+		}
+		return result, nil
+	}, 1, unlimitedParameters, false)
+
+	registry.registerIntegerFunction("lcm", func(arguments []int64) (int64, error) {
+		result := int64(1)
+		for _, argument := range arguments {
+			if argument == 0 {
+				return 0, nil
+			}
+			result = result / gcd(result, argument) * absInteger(argument)
+			if result > maxExactInteger {
+				return 0, fmt.Errorf("%w: the result exceeds 2^53", ErrIntegerOverflow)
+			}
+		}
+		return result, nil
+	}, 1, unlimitedParameters, false)
+
+	// 1 when the argument is a prime number, 0 otherwise
+	registry.registerIntegerFunction("isprime", func(arguments []int64) (int64, error) {
+		// ProbablyPrime is exact below 2^64
+		if arguments[0] > 1 && big.NewInt(arguments[0]).ProbablyPrime(0) {
+			return 1, nil
+		}
+		return 0, nil
+	}, 1, 1, false)
+
+	// fib(n), the Fibonacci number with fib(0) = 0 and fib(1) = 1
+	registry.registerIntegerFunction("fib", func(arguments []int64) (int64, error) {
+		if arguments[0] < 0 {
+			return 0, domainError("the argument must be a non-negative integer, got %d", arguments[0])
+		}
+
+		previous, current := int64(1), int64(0)
+		for i := int64(0); i < arguments[0]; i++ {
+			previous, current = current, previous+current
+			if current > maxExactInteger {
+				return 0, fmt.Errorf("%w: fib(%d) exceeds 2^53", ErrIntegerOverflow, arguments[0])
+			}
+		}
+		return current, nil
+	}, 1, 1, false)
+
+	// floordiv(a, b), the quotient rounded towards negative infinity
+	registry.registerIntegerFunction("floordiv", func(arguments []int64) (int64, error) {
+		a, b := arguments[0], arguments[1]
+		if b == 0 {
+			return 0, errors.New("division by zero")
+		}
+
+		quotient := a / b
+		if a%b != 0 && (a < 0) != (b < 0) {
+			quotient--
+		}
+		return quotient, nil
+	}, 2, 2, false)
+
+	// powmod(b, e, m), b^e modulo m, between 0 and m-1
+	registry.registerIntegerFunction("powmod", func(arguments []int64) (int64, error) {
+		base, exponent, modulus := arguments[0], arguments[1], arguments[2]
+		if exponent < 0 || modulus <= 0 {
+			return 0, domainError("expected a non-negative exponent and a positive modulus, got %d and %d", exponent, modulus)
+		}
+
+		m := big.NewInt(modulus)
+		b := new(big.Int).Mod(big.NewInt(base), m)
+		return new(big.Int).Exp(b, big.NewInt(exponent), m).Int64(), nil
+	}, 3, 3, false)
+
+	// the sum of the decimal digits of the absolute value
+	registry.registerIntegerFunction("digitsum", func(arguments []int64) (int64, error) {
+		sum := int64(0)
+		for _, digit := range strconv.FormatInt(absInteger(arguments[0]), 10) {
+			sum += int64(digit - '0')
+		}
+		return sum, nil
+	}, 1, 1, false)
+}
+
+func factorialFunction(arguments []int64) (int64, error) {
+	if arguments[0] < 0 {
+		return 0, domainError("the argument must be a non-negative integer, got %d", arguments[0])
+	}
+	return exactBigInteger(new(big.Int).MulRange(1, arguments[0]))
+}
+
+/*
+	Registers a function computing on integers: it fails when an argument is not an integer or is
+	larger than 2^53, and when its result is larger than 2^53.
+*/
+func (this *functionRegistry) registerIntegerFunction(name string, function integerDelegate, minParameters int, maxParameters int, isOverWritable bool) {
+	this.register(functionInfo{
+		name: name,
+		typedFunction: func(arguments []float64) (float64, error) {
+			integers := make([]int64, len(arguments))
+			for idx, argument := range arguments {
+				if argument != math.Trunc(argument) || math.Abs(argument) > maxExactInteger {
+					return 0, domainError("the argument %d must be an integer between -2^53 and 2^53, got %v", idx+1, argument)
+				}
+				integers[idx] = int64(argument)
+			}
+
+			result, err := function(integers)
+			if err != nil {
+				return 0, err
+			}
+			if absInteger(result) > maxExactInteger {
+				return 0, fmt.Errorf("%w: the result exceeds 2^53", ErrIntegerOverflow)
+			}
+			return float64(result), nil
+		},
+		minParameters:  minParameters,
+		maxParameters:  maxParameters,
+		isOverWritable: isOverWritable,
+		isIdempotent:   true,
+		isInteger:      true,
+	})
+}
+
+func exactBigInteger(value *big.Int) (int64, error) {
+	if value.CmpAbs(big.NewInt(maxExactInteger)) > 0 {
+		return 0, fmt.Errorf("%w: the result exceeds 2^53", ErrIntegerOverflow)
+	}
+	return value.Int64(), nil
+}
+
+func gcd(a int64, b int64) int64 {
+	a, b = absInteger(a), absInteger(b)
+	for b != 0 {
+		a, b = b, a%b
+	}
+	return a
+}
+
+func absInteger(value int64) int64 {
+	if value < 0 {
+		return -value
+	}
+	return value
+}
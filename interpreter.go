@@ -1,12 +1,20 @@
 package gojacego
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"math"
+	"strings"
+	"time"
 )
 
 type interpreter struct {
+	maxEvaluationSteps  int
+	nonFinitePolicy     NonFinitePolicy
+	nonFiniteSubstitute float64
+	missingVariables    missingVariables
+	converter           valueConverter
 }
 
 /*
@@ -14,177 +22,925 @@ type interpreter struct {
 */
 type Formula func(vars map[string]interface{}) (float64, error)
 
-func (*interpreter) execute(op operation, vars formulaVariables, functionRegistry *functionRegistry, constantRegistry *constantRegistry) (ret float64, err error) {
+/*
+	An Evaluator is a built formula that can be evaluated with a context, with options for a
+	single evaluation or with its result as a Value.
+*/
+type Evaluator struct {
+	executor         *interpreter
+	operation        operation
+	functionRegistry *functionRegistry
+	constantRegistry *constantRegistry
+}
+
+/*
+	Evaluate the formula using the given variables [vars], adjusted by [options].
+*/
+func (this *Evaluator) Eval(vars map[string]interface{}, options ...EvaluationOption) (float64, error) {
+	return this.EvalContext(context.Background(), vars, options...)
+}
+
+/*
+	Evaluate the formula using the given variables [vars], adjusted by [options]. The context [ctx] is
+	passed on to the context-aware functions and the evaluation stops with 'ctx.Err()' once it is cancelled.
+*/
+func (this *Evaluator) EvalContext(ctx context.Context, vars map[string]interface{}, options ...EvaluationOption) (float64, error) {
+	value, err := this.evaluate(ctx, vars, options)
+	if err != nil {
+		return 0, err
+	}
+	return value.toFloat64()
+}
+
+/*
+	Evaluate the formula using the given variables [vars], adjusted by [options], and return its
+	result as a Value, which can be null.
+*/
+func (this *Evaluator) EvalValue(vars map[string]interface{}, options ...EvaluationOption) (Value, error) {
+	return this.evaluate(context.Background(), vars, options)
+}
+
+/*
+	Return the evaluator as a Formula.
+*/
+func (this *Evaluator) Formula() Formula {
+	return func(vars map[string]interface{}) (float64, error) {
+		return this.Eval(vars)
+	}
+}
+
+func (this *Evaluator) evaluate(ctx context.Context, vars map[string]interface{}, options []EvaluationOption) (ret Value, err error) {
+
 	defer func() {
 		if r := recover(); r != nil {
-			err = errors.New(r.(string))
+			err = recoveredError(r)
 		}
 	}()
 
-	ret = execute(op, vars, functionRegistry, constantRegistry)
-	return ret, err
+	opts := evaluationOptions{missingVariables: this.executor.missingVariables}
+	for _, option := range options {
+		option.applyEvaluation(&opts)
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nullValue, err
+	}
+
+	state := &evaluationState{
+		ctx:                 withEvaluationRandom(ctx),
+		vars:                vars,
+		functionRegistry:    this.functionRegistry,
+		constantRegistry:    this.constantRegistry,
+		maxEvaluationSteps:  this.executor.maxEvaluationSteps,
+		nonFinitePolicy:     this.executor.nonFinitePolicy,
+		nonFiniteSubstitute: this.executor.nonFiniteSubstitute,
+		missingVariables:    opts.missingVariables,
+		converter:           this.executor.converter,
+	}
+
+	return execute(this.operation, state), nil
 }
 
-func (*interpreter) buildFormula(op operation, functionRegistry *functionRegistry, constantRegistry *constantRegistry) Formula {
-	return func(vars map[string]interface{}) (ret float64, err error) {
+type evaluationOptions struct {
+	missingVariables missingVariables
+}
 
-		defer func() {
-			if r := recover(); r != nil {
-				err = errors.New(r.(string))
-			}
-		}()
+/*
+	EvaluationOption configures a single evaluation of an Evaluator.
+*/
+type EvaluationOption interface {
+	applyEvaluation(*evaluationOptions)
+}
+
+type applyEvaluationOptions struct {
+	f func(*evaluationOptions)
+}
+
+func (apply *applyEvaluationOptions) applyEvaluation(opts *evaluationOptions) {
+	apply.f(opts)
+}
+
+/*
+	Choose the value used for the variables that are not given to this evaluation, overriding the
+	policy of the engine.
+*/
+func UsingMissingVariablePolicy(policy MissingVariablePolicy) EvaluationOption {
+	return &applyEvaluationOptions{
+		f: func(options *evaluationOptions) {
+			options.missingVariables.policy = policy
+		},
+	}
+}
+
+/*
+	Use the values of [defaults] for the variables that are not given to this evaluation.
+	It selects the MissingVariableDefault policy.
+*/
+func UsingVariableDefaults(defaults map[string]float64) EvaluationOption {
+	return &applyEvaluationOptions{
+		f: func(options *evaluationOptions) {
+			options.missingVariables = missingVariables{policy: MissingVariableDefault, defaults: defaults}
+		},
+	}
+}
+
+// Tells what value is used for the variables that are not given to an evaluation.
+type missingVariables struct {
+	policy   MissingVariablePolicy
+	defaults map[string]float64
+}
+
+// Holds everything an evaluation of an operation tree needs.
+type evaluationState struct {
+	ctx                 context.Context
+	vars                formulaVariables
+	functionRegistry    *functionRegistry
+	constantRegistry    *constantRegistry
+	steps               int
+	maxEvaluationSteps  int
+	nonFinitePolicy     NonFinitePolicy
+	nonFiniteSubstitute float64
+	missingVariables    missingVariables
+	converter           valueConverter
+}
+
+func (this *interpreter) execute(op operation, vars formulaVariables, functionRegistry *functionRegistry, constantRegistry *constantRegistry) (float64, error) {
+	ret, err := this.evaluate(op, vars, functionRegistry, constantRegistry)
+	if err != nil {
+		return 0, err
+	}
+	return ret.toFloat64()
+}
 
-		ret = execute(op, vars, functionRegistry, constantRegistry)
-		return ret, err
+func (this *interpreter) evaluate(op operation, vars formulaVariables, functionRegistry *functionRegistry, constantRegistry *constantRegistry) (ret Value, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = recoveredError(r)
+		}
+	}()
+
+	state := &evaluationState{
+		ctx:                 context.Background(),
+		vars:                vars,
+		functionRegistry:    functionRegistry,
+		constantRegistry:    constantRegistry,
+		maxEvaluationSteps:  this.maxEvaluationSteps,
+		nonFinitePolicy:     this.nonFinitePolicy,
+		nonFiniteSubstitute: this.nonFiniteSubstitute,
+		missingVariables:    this.missingVariables,
+		converter:           this.converter,
 	}
+
+	ret = execute(op, state)
+	return ret, err
+}
+
+func (this *interpreter) buildEvaluator(op operation, functionRegistry *functionRegistry, constantRegistry *constantRegistry) *Evaluator {
+	return &Evaluator{executor: this, operation: op, functionRegistry: functionRegistry, constantRegistry: constantRegistry}
 }
 
-func execute(op operation, vars formulaVariables, functionRegistry *functionRegistry, constantRegistry *constantRegistry) float64 {
+func execute(op operation, state *evaluationState) Value {
 
 	if op == nil {
 		panic("operation cannot be nil")
 	}
 
+	state.steps++
+	if state.maxEvaluationSteps > 0 && state.steps > state.maxEvaluationSteps {
+		panic(&MaxEvaluationStepsError{Limit: state.maxEvaluationSteps})
+	}
+
 	if cop, ok := op.(*constantOperation); ok {
-		if cop.Metadata.DataType == integer {
-			return toFloat64Panic(cop.Value)
-		} else {
-			return cop.Value.(float64)
+		switch cop.Metadata.DataType {
+		case integer:
+			return NumberValue(toFloat64Panic(cop.Value))
+		case text:
+			return StringValue(cop.Value.(string))
+		case dateTime:
+			return DateValue(cop.Value.(time.Time))
+		case quantity:
+			return cop.Value.(Value)
+		default:
+			return NumberValue(cop.Value.(float64))
 		}
 
 	} else if cop, ok := op.(*variableOperation); ok {
 
-		variableValue, err := vars.Get(cop.Name)
-		if err == nil {
-			return toFloat64Panic(variableValue)
-		} else {
-			panic("The variable '" + cop.Name + "' used is not defined.")
+		variableValue, err := state.vars.Get(cop.Name)
+		if err != nil {
+			return state.missingVariable(cop)
+		}
+
+		ret, err := state.converter.toValue(variableValue)
+		if err != nil {
+			panic(&TypeError{Code: ErrorCodeTypeMismatch,
+				Message:  fmt.Sprintf("the variable '%s' of type %T cannot be converted to float64", cop.Name, variableValue),
+				Token:    cop.Name,
+				Position: cop.Position,
+				Length:   cop.Length})
+		}
+
+		if cop.Unit != nil {
+			return withVariableUnit(ret, cop)
+		}
+		if ret.kind != KindNumber {
+			return ret
 		}
+		return state.checkFinite(ret.number, cop)
 
 	} else if cop, ok := op.(*multiplicationOperation); ok {
-		left := execute(cop.OperationOne, vars, functionRegistry, constantRegistry)
-		right := execute(cop.OperationTwo, vars, functionRegistry, constantRegistry)
+		left := execute(cop.OperationOne, state)
+		right := execute(cop.OperationTwo, state)
 
-		return left * right
+		if left.IsNull() || right.IsNull() {
+			return nullValue
+		}
+		if left.kind != KindNumber || right.kind != KindNumber {
+			return state.arithmetic("*", left, right, cop)
+		}
+		return state.checkFinite(left.number*right.number, cop)
 	} else if cop, ok := op.(*addOperation); ok {
-		left := execute(cop.OperationOne, vars, functionRegistry, constantRegistry)
-		right := execute(cop.OperationTwo, vars, functionRegistry, constantRegistry)
+		left := execute(cop.OperationOne, state)
+		right := execute(cop.OperationTwo, state)
 
-		return left + right
+		if left.IsNull() || right.IsNull() {
+			return nullValue
+		}
+		if left.kind != KindNumber || right.kind != KindNumber {
+			return state.arithmetic("+", left, right, cop)
+		}
+		return state.checkFinite(left.number+right.number, cop)
 	} else if cop, ok := op.(*subtractionOperation); ok {
-		left := execute(cop.OperationOne, vars, functionRegistry, constantRegistry)
-		right := execute(cop.OperationTwo, vars, functionRegistry, constantRegistry)
+		left := execute(cop.OperationOne, state)
+		right := execute(cop.OperationTwo, state)
 
-		return left - right
+		if left.IsNull() || right.IsNull() {
+			return nullValue
+		}
+		if left.kind != KindNumber || right.kind != KindNumber {
+			return state.arithmetic("-", left, right, cop)
+		}
+		return state.checkFinite(left.number-right.number, cop)
 	} else if cop, ok := op.(*divisorOperation); ok {
-		left := execute(cop.Dividend, vars, functionRegistry, constantRegistry)
-		right := execute(cop.Divisor, vars, functionRegistry, constantRegistry)
+		left := execute(cop.Dividend, state)
+		right := execute(cop.Divisor, state)
 
-		return left / right
+		if left.IsNull() || right.IsNull() {
+			return nullValue
+		}
+		if left.kind != KindNumber || right.kind != KindNumber {
+			return state.arithmetic("/", left, right, cop)
+		}
+		return state.checkFinite(left.number/right.number, cop)
 	} else if cop, ok := op.(*moduloOperation); ok {
-		left := execute(cop.Dividend, vars, functionRegistry, constantRegistry)
-		right := execute(cop.Divisor, vars, functionRegistry, constantRegistry)
+		left := execute(cop.Dividend, state)
+		right := execute(cop.Divisor, state)
+
+		if left.IsNull() || right.IsNull() {
+			return nullValue
+		}
+		if left.kind != KindNumber || right.kind != KindNumber {
+			return state.arithmetic("%", left, right, cop)
+		}
+		return state.checkFinite(math.Mod(left.number, right.number), cop)
+	} else if cop, ok := op.(*integerDivisionOperation); ok {
+		left := execute(cop.Dividend, state)
+		right := execute(cop.Divisor, state)
 
-		return math.Mod(left, right)
+		if left.IsNull() || right.IsNull() {
+			return nullValue
+		}
+		if left.kind != KindNumber || right.kind != KindNumber {
+			panic(newOperatorTypeError("//", cop, left, right))
+		}
+		return state.checkFinite(math.Floor(left.number/right.number), cop)
+	} else if cop, ok := op.(*bitwiseAndOperation); ok {
+		operands, isNull := state.integerOperands("&", cop, cop.OperationOne, cop.OperationTwo)
+		if isNull {
+			return nullValue
+		}
+		return NumberValue(float64(operands[0] & operands[1]))
+	} else if cop, ok := op.(*bitwiseOrOperation); ok {
+		operands, isNull := state.integerOperands("|", cop, cop.OperationOne, cop.OperationTwo)
+		if isNull {
+			return nullValue
+		}
+		return NumberValue(float64(operands[0] | operands[1]))
+	} else if cop, ok := op.(*bitwiseXorOperation); ok {
+		operands, isNull := state.integerOperands("xor", cop, cop.OperationOne, cop.OperationTwo)
+		if isNull {
+			return nullValue
+		}
+		return NumberValue(float64(operands[0] ^ operands[1]))
+	} else if cop, ok := op.(*bitwiseNotOperation); ok {
+		operands, isNull := state.integerOperands("~", cop, cop.Operation)
+		if isNull {
+			return nullValue
+		}
+		return NumberValue(float64(^operands[0]))
+	} else if cop, ok := op.(*leftShiftOperation); ok {
+		operands, isNull := state.integerOperands("<<", cop, cop.OperationOne, cop.OperationTwo)
+		if isNull {
+			return nullValue
+		}
+		// a multiplication by a power of two, which does not overflow like the shift of an int64
+		return state.checkFinite(math.Ldexp(float64(operands[0]), shiftCount("<<", cop, operands[1])), cop)
+	} else if cop, ok := op.(*rightShiftOperation); ok {
+		operands, isNull := state.integerOperands(">>", cop, cop.OperationOne, cop.OperationTwo)
+		if isNull {
+			return nullValue
+		}
+		return NumberValue(float64(operands[0] >> uint(shiftCount(">>", cop, operands[1]))))
 	} else if cop, ok := op.(*exponentiationOperation); ok {
-		left := execute(cop.Base, vars, functionRegistry, constantRegistry)
-		right := execute(cop.Exponent, vars, functionRegistry, constantRegistry)
+		left := execute(cop.Base, state)
+		right := execute(cop.Exponent, state)
 
-		return math.Pow(left, right)
+		if left.IsNull() || right.IsNull() {
+			return nullValue
+		}
+		if left.kind != KindNumber || right.kind != KindNumber {
+			return state.arithmetic("^", left, right, cop)
+		}
+		return state.checkFinite(math.Pow(left.number, right.number), cop)
 	} else if cop, ok := op.(*unaryMinusOperation); ok {
-		arg := execute(cop.Operation, vars, functionRegistry, constantRegistry)
-		return -arg
+		arg := execute(cop.Operation, state)
+
+		if arg.IsNull() {
+			return nullValue
+		}
+		if arg.kind == KindDuration {
+			return DurationValue(-arg.duration)
+		}
+		if arg.kind == KindQuantity {
+			return Value{kind: KindQuantity, number: -arg.number, data: arg.data}
+		}
+		if arg.kind != KindNumber {
+			panic(newOperatorTypeError("-", cop, arg))
+		}
+		return state.checkFinite(-arg.number, cop)
+	} else if cop, ok := op.(*factorialOperation); ok {
+		arg := execute(cop.Operation, state)
+
+		if arg.IsNull() {
+			return nullValue
+		}
+		if arg.kind != KindNumber {
+			panic(newOperatorTypeError("!", cop, arg))
+		}
+		result, err := floatFactorial(arg.number)
+		if err != nil {
+			panic(newDomainError(err, cop))
+		}
+		return NumberValue(result)
+	} else if cop, ok := op.(*percentageOperation); ok {
+		arg := execute(cop.Operation, state)
+
+		if arg.IsNull() {
+			return nullValue
+		}
+		if arg.kind == KindQuantity {
+			return Value{kind: KindQuantity, number: arg.number / 100, data: arg.data}
+		}
+		if arg.kind != KindNumber {
+			panic(newOperatorTypeError("%", cop, arg))
+		}
+		return state.checkFinite(arg.number/100, cop)
 	} else if cop, ok := op.(*andOperation); ok {
-		left := execute(cop.OperationOne, vars, functionRegistry, constantRegistry)
-		right := execute(cop.OperationTwo, vars, functionRegistry, constantRegistry)
+		left := execute(cop.OperationOne, state)
+		right := execute(cop.OperationTwo, state)
+
+		checkLogical("&&", cop, left, right)
 
-		if left != 0 && right != 0 {
-			return 1.0
+		// three-valued logic: false wins over null
+		if isFalse(left) || isFalse(right) {
+			return booleanValue(false)
 		}
-		return 0.0
+		if left.IsNull() || right.IsNull() {
+			return nullValue
+		}
+		return booleanValue(true)
 	} else if cop, ok := op.(*orOperation); ok {
-		left := execute(cop.OperationOne, vars, functionRegistry, constantRegistry)
-		right := execute(cop.OperationTwo, vars, functionRegistry, constantRegistry)
+		left := execute(cop.OperationOne, state)
+		right := execute(cop.OperationTwo, state)
+
+		checkLogical("||", cop, left, right)
 
-		if left != 0 || right != 0 {
-			return 1.0
+		// three-valued logic: true wins over null
+		if isTrue(left) || isTrue(right) {
+			return booleanValue(true)
 		}
-		return 0.0
+		if left.IsNull() || right.IsNull() {
+			return nullValue
+		}
+		return booleanValue(false)
 	} else if cop, ok := op.(*lessThanOperation); ok {
-		left := execute(cop.OperationOne, vars, functionRegistry, constantRegistry)
-		right := execute(cop.OperationTwo, vars, functionRegistry, constantRegistry)
+		left := execute(cop.OperationOne, state)
+		right := execute(cop.OperationTwo, state)
 
-		if left < right {
-			return 1.0
+		if left.IsNull() || right.IsNull() {
+			return nullValue
+		}
+		if left.kind != KindNumber || right.kind != KindNumber {
+			return booleanValue(compareValues("<", left, right, cop) < 0)
 		}
-		return 0.0
+		return booleanValue(left.number < right.number)
 	} else if cop, ok := op.(*lessOrEqualThanOperation); ok {
-		left := execute(cop.OperationOne, vars, functionRegistry, constantRegistry)
-		right := execute(cop.OperationTwo, vars, functionRegistry, constantRegistry)
+		left := execute(cop.OperationOne, state)
+		right := execute(cop.OperationTwo, state)
 
-		if left <= right {
-			return 1.0
+		if left.IsNull() || right.IsNull() {
+			return nullValue
 		}
-		return 0.0
+		if left.kind != KindNumber || right.kind != KindNumber {
+			return booleanValue(compareValues("<=", left, right, cop) <= 0)
+		}
+		return booleanValue(left.number <= right.number)
 	} else if cop, ok := op.(*greaterThanOperation); ok {
-		left := execute(cop.OperationOne, vars, functionRegistry, constantRegistry)
-		right := execute(cop.OperationTwo, vars, functionRegistry, constantRegistry)
+		left := execute(cop.OperationOne, state)
+		right := execute(cop.OperationTwo, state)
 
-		if left > right {
-			return 1.0
+		if left.IsNull() || right.IsNull() {
+			return nullValue
+		}
+		if left.kind != KindNumber || right.kind != KindNumber {
+			return booleanValue(compareValues(">", left, right, cop) > 0)
 		}
-		return 0.0
+		return booleanValue(left.number > right.number)
 	} else if cop, ok := op.(*greaterOrEqualThanOperation); ok {
-		left := execute(cop.OperationOne, vars, functionRegistry, constantRegistry)
-		right := execute(cop.OperationTwo, vars, functionRegistry, constantRegistry)
+		left := execute(cop.OperationOne, state)
+		right := execute(cop.OperationTwo, state)
 
-		if left >= right {
-			return 1.0
+		if left.IsNull() || right.IsNull() {
+			return nullValue
 		}
-		return 0.0
+		if left.kind != KindNumber || right.kind != KindNumber {
+			return booleanValue(compareValues(">=", left, right, cop) >= 0)
+		}
+		return booleanValue(left.number >= right.number)
 	} else if cop, ok := op.(*equalOperation); ok {
-		left := execute(cop.OperationOne, vars, functionRegistry, constantRegistry)
-		right := execute(cop.OperationTwo, vars, functionRegistry, constantRegistry)
+		left := execute(cop.OperationOne, state)
+		right := execute(cop.OperationTwo, state)
 
-		if left == right {
-			return 1.0
+		if left.IsNull() || right.IsNull() {
+			return nullValue
+		}
+		if left.kind != KindNumber || right.kind != KindNumber {
+			return booleanValue(compareValues("==", left, right, cop) == 0)
 		}
-		return 0.0
+		return booleanValue(left.number == right.number)
 	} else if cop, ok := op.(*notEqualOperation); ok {
-		left := execute(cop.OperationOne, vars, functionRegistry, constantRegistry)
-		right := execute(cop.OperationTwo, vars, functionRegistry, constantRegistry)
+		left := execute(cop.OperationOne, state)
+		right := execute(cop.OperationTwo, state)
 
-		if left != right {
-			return 1.0
+		if left.IsNull() || right.IsNull() {
+			return nullValue
 		}
-		return 0.0
+		if left.kind != KindNumber || right.kind != KindNumber {
+			return booleanValue(compareValues("!=", left, right, cop) != 0)
+		}
+		return booleanValue(left.number != right.number)
+	} else if cop, ok := op.(*concatOperation); ok {
+		left := execute(cop.OperationOne, state)
+		right := execute(cop.OperationTwo, state)
+
+		// like an empty cell, null is an empty string
+		return StringValue(concatText(left) + concatText(right))
 	} else if cop, ok := op.(*functionOperation); ok {
 
-		fn, _ := functionRegistry.get(cop.Name)
-		arguments := make([]interface{}, len(cop.Arguments))
+		fn, _ := state.functionRegistry.get(cop.Name)
+
+		if err := fn.validateNumberOfParameters(len(cop.Arguments)); err != nil {
+			panic(newFunctionError(ErrorCodeInvalidArguments, cop, err))
+		}
+
+		if fn.variableFunction != nil {
+			return executeVariableFunction(fn, cop, state)
+		}
+
+		if fn.lazyFunction != nil {
+			return executeLazyFunction(fn, cop, state)
+		}
+
+		values := make([]Value, len(cop.Arguments))
+		hasNullArgument := false
 
 		for idx, fnParam := range cop.Arguments {
-			arg := execute(fnParam, vars, functionRegistry, constantRegistry)
-			arguments[idx] = arg
+			values[idx] = execute(fnParam, state)
+			hasNullArgument = hasNullArgument || values[idx].IsNull()
 		}
+
+		if err := state.ctx.Err(); err != nil {
+			panic(err)
+		}
+
+		if fn.valueFunction != nil {
+			ret, err := fn.valueFunction(values)
+			if err != nil {
+				panic(newFunctionError(ErrorCodeFunctionFailed, cop, err))
+			}
+			if ret.kind != KindNumber {
+				return ret
+			}
+			return state.checkFinite(ret.number, cop)
+		}
+
+		// the functions that do not handle null values return null when one of their arguments is null
+		if hasNullArgument {
+			return nullValue
+		}
+
+		for idx, value := range values {
+			if value.kind != KindNumber {
+				panic(newFunctionError(ErrorCodeInvalidArguments, cop,
+					fmt.Errorf("the argument %d is a %s, expected a number", idx+1, value.kind)))
+			}
+		}
+
+		if fn.typedFunction != nil || fn.contextFunction != nil {
+			arguments := make([]float64, len(values))
+			for idx, value := range values {
+				arguments[idx] = value.number
+			}
+
+			ret, err := runTypedDelegate(state.ctx, fn, arguments)
+			if err != nil {
+				panic(newFunctionError(ErrorCodeFunctionFailed, cop, err))
+			}
+			return state.checkFinite(ret, cop)
+		}
+
+		arguments := make([]interface{}, len(values))
+		for idx, value := range values {
+			arguments[idx] = value.number
+		}
+
 		ret, err := runDelegate(fn, arguments)
 		if err != nil {
-			panic(err.Error())
+			panic(newFunctionError(ErrorCodeFunctionFailed, cop, err))
 		}
-		return ret
+		return state.checkFinite(ret, cop)
 	}
 
 	panic(fmt.Sprintf("not implemented %T", op))
 }
 
+// Returns the value of a variable that is not given to the evaluation, according to the missing variable policy.
+func (this *evaluationState) missingVariable(op *variableOperation) Value {
+	switch this.missingVariables.policy {
+	case MissingVariableZero:
+		return NumberValue(0.0)
+	case MissingVariableNaN:
+		return NumberValue(math.NaN())
+	case MissingVariableDefault:
+		if value, found := this.missingVariables.defaults[op.Name]; found {
+			return NumberValue(value)
+		}
+	}
+
+	panic(&UnknownVariableError{Code: ErrorCodeUnknownVariable, Name: op.Name, Position: op.Position, Length: op.Length})
+}
+
+// Calls a function whose first argument can be a variable that is not defined, in which case it is not evaluated.
+func executeVariableFunction(fn *functionInfo, op *functionOperation, state *evaluationState) Value {
+	isDefined := true
+	if variable, ok := op.Arguments[0].(*variableOperation); ok {
+		_, err := state.vars.Get(variable.Name)
+		isDefined = err == nil
+	}
+
+	arguments := make([]Value, len(op.Arguments))
+	for idx, fnParam := range op.Arguments {
+		if idx > 0 || isDefined {
+			arguments[idx] = execute(fnParam, state)
+		}
+	}
+
+	ret, err := fn.variableFunction(isDefined, arguments)
+	if err != nil {
+		panic(newFunctionError(ErrorCodeFunctionFailed, op, err))
+	}
+	if ret.kind != KindNumber {
+		return ret
+	}
+	return state.checkFinite(ret.number, op)
+}
+
+// An argument of a lazy function, evaluated on demand.
+type lazyArgument struct {
+	op    operation
+	state *evaluationState
+}
+
+// Evaluates the argument, its errors stop the evaluation of the formula.
+func (this lazyArgument) value() Value {
+	return execute(this.op, this.state)
+}
+
+/*
+	Evaluates the argument and returns its error instead of stopping the evaluation of the formula.
+	Exceeding a limit and the cancellation of the context still stop it.
+*/
+func (this lazyArgument) try() (ret Value, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = recoveredError(r)
+
+			var maxEvaluationStepsError *MaxEvaluationStepsError
+			if errors.As(err, &maxEvaluationStepsError) || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+				panic(r)
+			}
+		}
+	}()
+
+	return execute(this.op, this.state), nil
+}
+
+// Calls a function that evaluates its arguments itself.
+func executeLazyFunction(fn *functionInfo, op *functionOperation, state *evaluationState) Value {
+	arguments := make([]lazyArgument, len(op.Arguments))
+	for idx, fnParam := range op.Arguments {
+		arguments[idx] = lazyArgument{op: fnParam, state: state}
+	}
+
+	ret, err := fn.lazyFunction(arguments)
+	if err != nil {
+		panic(newFunctionError(ErrorCodeFunctionFailed, op, err))
+	}
+	if ret.kind != KindNumber {
+		return ret
+	}
+	return state.checkFinite(ret.number, op)
+}
+
+// Returns the text of a value that is concatenated.
+func concatText(value Value) string {
+	if value.IsNull() {
+		return ""
+	}
+	return value.String()
+}
+
+// Applies the non-finite policy to the value produced by the operation [op].
+func (this *evaluationState) checkFinite(value float64, op operation) Value {
+	if this.nonFinitePolicy == PropagateNonFinite || !(math.IsNaN(value) || math.IsInf(value, 0)) {
+		return NumberValue(value)
+	}
+
+	if this.nonFinitePolicy == SubstituteNonFinite {
+		return NumberValue(this.nonFiniteSubstitute)
+	}
+
+	panic(newArithmeticError(value, op))
+}
+
+func newArithmeticError(value float64, op operation) *ArithmeticError {
+	name, position, length := describeOperation(op)
+	return &ArithmeticError{Code: ErrorCodeArithmetic, Operation: name, Value: value, Position: position, Length: length}
+}
+
+func newDomainError(err error, op operation) *ArithmeticError {
+	arithmeticErr := newArithmeticError(math.NaN(), op)
+	arithmeticErr.Err = err
+	return arithmeticErr
+}
+
+// Returns the name of the operation [op] and where it is in the formula.
+func describeOperation(op operation) (string, int, int) {
+	switch cop := op.(type) {
+	case *variableOperation:
+		return fmt.Sprintf("variable '%s'", cop.Name), cop.Position, cop.Length
+	case *functionOperation:
+		return fmt.Sprintf("function '%s'", cop.Name), cop.Position, cop.Length
+	case *addOperation:
+		return "addition", cop.Position, cop.Length
+	case *subtractionOperation:
+		return "subtraction", cop.Position, cop.Length
+	case *multiplicationOperation:
+		return "multiplication", cop.Position, cop.Length
+	case *divisorOperation:
+		return "division", cop.Position, cop.Length
+	case *moduloOperation:
+		return "modulo", cop.Position, cop.Length
+	case *exponentiationOperation:
+		return "exponentiation", cop.Position, cop.Length
+	case *unaryMinusOperation:
+		return "negation", cop.Position, cop.Length
+	case *integerDivisionOperation:
+		return "integer division", cop.Position, cop.Length
+	case *bitwiseAndOperation:
+		return "bitwise and", cop.Position, cop.Length
+	case *bitwiseOrOperation:
+		return "bitwise or", cop.Position, cop.Length
+	case *bitwiseXorOperation:
+		return "bitwise xor", cop.Position, cop.Length
+	case *bitwiseNotOperation:
+		return "bitwise not", cop.Position, cop.Length
+	case *leftShiftOperation:
+		return "left shift", cop.Position, cop.Length
+	case *rightShiftOperation:
+		return "right shift", cop.Position, cop.Length
+	case *factorialOperation:
+		return "factorial", cop.Position, cop.Length
+	case *percentageOperation:
+		return "percentage", cop.Position, cop.Length
+	case *concatOperation:
+		return "concatenation", cop.Position, cop.Length
+	case *andOperation:
+		return "logical and", cop.Position, cop.Length
+	case *orOperation:
+		return "logical or", cop.Position, cop.Length
+	case *lessThanOperation:
+		return "comparison", cop.Position, cop.Length
+	case *lessOrEqualThanOperation:
+		return "comparison", cop.Position, cop.Length
+	case *greaterThanOperation:
+		return "comparison", cop.Position, cop.Length
+	case *greaterOrEqualThanOperation:
+		return "comparison", cop.Position, cop.Length
+	case *equalOperation:
+		return "comparison", cop.Position, cop.Length
+	case *notEqualOperation:
+		return "comparison", cop.Position, cop.Length
+	}
+	return fmt.Sprintf("%T", op), 0, 0
+}
+
+/*
+	Applies an arithmetic operator to values that are not all numbers: a duration can be added to or
+	subtracted from a date, two dates subtracted to give a duration, and durations added, subtracted,
+	divided or scaled by a number.
+*/
+func (this *evaluationState) arithmetic(operator string, left Value, right Value, op operation) Value {
+	if left.kind == KindQuantity || right.kind == KindQuantity {
+		return this.quantityArithmetic(operator, left, right, op)
+	}
+
+	switch {
+	case left.kind == KindDate && right.kind == KindDuration && operator == "+":
+		return DateValue(left.data.(time.Time).Add(right.duration))
+	case left.kind == KindDate && right.kind == KindDuration && operator == "-":
+		return DateValue(left.data.(time.Time).Add(-right.duration))
+	case left.kind == KindDuration && right.kind == KindDate && operator == "+":
+		return DateValue(right.data.(time.Time).Add(left.duration))
+	case left.kind == KindDate && right.kind == KindDate && operator == "-":
+		return DurationValue(left.data.(time.Time).Sub(right.data.(time.Time)))
+	case left.kind == KindDuration && right.kind == KindDuration:
+		switch operator {
+		case "+":
+			return DurationValue(left.duration + right.duration)
+		case "-":
+			return DurationValue(left.duration - right.duration)
+		case "/":
+			return this.checkFinite(float64(left.duration)/float64(right.duration), op)
+		}
+	case left.kind == KindDuration && right.kind == KindNumber && operator == "*":
+		return toDuration(float64(left.duration)*right.number, op)
+	case left.kind == KindDuration && right.kind == KindNumber && operator == "/":
+		return toDuration(float64(left.duration)/right.number, op)
+	case left.kind == KindNumber && right.kind == KindDuration && operator == "*":
+		return toDuration(left.number*float64(right.duration), op)
+	}
+
+	panic(newOperatorTypeError(operator, op, left, right))
+}
+
+// Converts the nanoseconds computed by the operation [op] to a duration.
+func toDuration(nanoseconds float64, op operation) Value {
+	if math.IsNaN(nanoseconds) || math.Abs(nanoseconds) >= math.MaxInt64 {
+		panic(newArithmeticError(nanoseconds, op))
+	}
+	return DurationValue(time.Duration(math.Round(nanoseconds)))
+}
+
+// Compares two values of the same kind, that are not both numbers, and returns -1, 0 or 1.
+func compareValues(operator string, left Value, right Value, op operation) int {
+	if left.kind == right.kind {
+		switch left.kind {
+		case KindDate:
+			switch l, r := left.data.(time.Time), right.data.(time.Time); {
+			case l.Before(r):
+				return -1
+			case l.After(r):
+				return 1
+			}
+			return 0
+		case KindDuration:
+			switch {
+			case left.duration < right.duration:
+				return -1
+			case left.duration > right.duration:
+				return 1
+			}
+			return 0
+		case KindString:
+			return strings.Compare(left.data.(string), right.data.(string))
+		case KindQuantity:
+			return compareQuantities(operator, left, right, op)
+		}
+	}
+
+	panic(newOperatorTypeError(operator, op, left, right))
+}
+
+// Verifies that the operands of a logical operator are numbers or null.
+func checkLogical(operator string, op operation, values ...Value) {
+	for _, value := range values {
+		if value.kind != KindNumber && value.kind != KindNull {
+			panic(newOperatorTypeError(operator, op, values...))
+		}
+	}
+}
+
+/*
+	Evaluates the operands of a bitwise operator, which must be integers between -2^53 and 2^53.
+	Returns whether one of them is null, in which case the result is null.
+*/
+func (this *evaluationState) integerOperands(operator string, op operation, operands ...operation) ([]int64, bool) {
+	values := make([]Value, len(operands))
+	isNull := false
+	for idx, operand := range operands {
+		values[idx] = execute(operand, this)
+		isNull = isNull || values[idx].IsNull()
+	}
+
+	if isNull {
+		return nil, true
+	}
+
+	integers := make([]int64, len(values))
+	for idx, value := range values {
+		if value.kind != KindNumber {
+			panic(newOperatorTypeError(operator, op, values...))
+		}
+		if value.number != math.Trunc(value.number) || math.Abs(value.number) > maxExactInteger {
+			panic(newInvalidOperandError(operator, op, fmt.Sprintf("requires integers between -2^53 and 2^53, got %v", value.number)))
+		}
+		integers[idx] = int64(value.number)
+	}
+	return integers, false
+}
+
+// The number of bits of a shift, which cannot be negative.
+func shiftCount(operator string, op operation, count int64) int {
+	if count < 0 {
+		panic(newInvalidOperandError(operator, op, fmt.Sprintf("cannot shift by a negative count, got %d", count)))
+	}
+	if count > 2048 {
+		// the result is zero or an infinity from there
+		return 2048
+	}
+	return int(count)
+}
+
+func newInvalidOperandError(operator string, op operation, reason string) *TypeError {
+	_, position, length := describeOperation(op)
+	return &TypeError{Code: ErrorCodeInvalidOperand,
+		Message:  fmt.Sprintf("the operator '%s' %s", operator, reason),
+		Token:    operator,
+		Position: position,
+		Length:   length}
+}
+
+func newOperatorTypeError(operator string, op operation, values ...Value) *TypeError {
+	kinds := make([]string, len(values))
+	for idx, value := range values {
+		kinds[idx] = "a " + value.kind.String()
+	}
+
+	_, position, length := describeOperation(op)
+	return &TypeError{Code: ErrorCodeTypeMismatch,
+		Message:  fmt.Sprintf("the operator '%s' cannot be applied to %s", operator, strings.Join(kinds, " and ")),
+		Token:    operator,
+		Position: position,
+		Length:   length}
+}
+
+func newFunctionError(code ErrorCode, op *functionOperation, err error) *FunctionError {
+	return &FunctionError{Code: code, Name: op.Name, Position: op.Position, Length: op.Length, Err: err}
+}
+
 func runDelegate(fn *functionInfo, arguments []interface{}) (ret float64, err error) {
 
 	defer func() {
 		if r := recover(); r != nil {
-			err = fmt.Errorf("function '%s': runtime error (%T)", fn.name, r)
+			err = fmt.Errorf("runtime error (%T)", r)
 		}
 	}()
 
 	ret = fn.function(arguments...)
 	return ret, err
 }
+
+func runTypedDelegate(ctx context.Context, fn *functionInfo, arguments []float64) (ret float64, err error) {
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("runtime error (%T)", r)
+		}
+	}()
+
+	if fn.contextFunction != nil {
+		return fn.contextFunction(ctx, arguments)
+	}
+	return fn.typedFunction(arguments)
+}
@@ -1,6 +1,14 @@
 package gojacego
 
-import "errors"
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"reflect"
+	"strconv"
+	"strings"
+)
 
 func toFloat64(value interface{}) (float64, error) {
 	switch value.(type) {
@@ -58,3 +66,61 @@ func toFloat64Panic(value interface{}) float64 {
 
 	panic("cannot convert parameter to float64")
 }
+
+/*
+	Converts the values that 'toFloat64' rejects: booleans, numeral strings, 'json.Number', the big
+	numbers and the types whose underlying type is numeric, boolean or string.
+*/
+func convertToFloat64(value interface{}) (float64, error) {
+	switch v := value.(type) {
+	case bool:
+		if v {
+			return 1.0, nil
+		}
+		return 0.0, nil
+	case string:
+		return parseFloat64(v)
+	case json.Number:
+		return parseFloat64(string(v))
+	case *big.Float:
+		if v != nil {
+			ret, _ := v.Float64()
+			return ret, nil
+		}
+	case *big.Rat:
+		if v != nil {
+			ret, _ := v.Float64()
+			return ret, nil
+		}
+	case *big.Int:
+		if v != nil {
+			ret, _ := new(big.Float).SetInt(v).Float64()
+			return ret, nil
+		}
+	}
+
+	reflected := reflect.ValueOf(value)
+
+	switch reflected.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(reflected.Int()), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return float64(reflected.Uint()), nil
+	case reflect.Float32, reflect.Float64:
+		return reflected.Float(), nil
+	case reflect.Bool:
+		return convertToFloat64(reflected.Bool())
+	case reflect.String:
+		return parseFloat64(reflected.String())
+	}
+
+	return 0, fmt.Errorf("cannot convert %T to float64", value)
+}
+
+func parseFloat64(text string) (float64, error) {
+	ret, err := strconv.ParseFloat(strings.TrimSpace(text), 64)
+	if err != nil {
+		return 0, fmt.Errorf("cannot convert '%s' to float64", text)
+	}
+	return ret, nil
+}
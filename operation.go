@@ -5,6 +5,9 @@ type operationDataType int
 const (
 	integer operationDataType = iota
 	floatingPoint
+	text
+	dateTime
+	quantity
 )
 
 type operationMetadata struct {
@@ -22,6 +25,10 @@ type operation interface {
 // Variable
 type variableOperation struct {
 	Name     string
+	Position int
+	Length   int
+	// the unit of the annotated variables (i.e. 'speed[m/s]')
+	Unit     *unit
 	Metadata operationMetadata
 }
 
@@ -44,6 +51,8 @@ func newVariableOperation(name string) *variableOperation {
 type addOperation struct {
 	OperationOne operation
 	OperationTwo operation
+	Position     int
+	Length       int
 	Metadata     operationMetadata
 }
 
@@ -68,6 +77,8 @@ func newAddOperation(dataType operationDataType, operationOne operation, operati
 type andOperation struct {
 	OperationOne operation
 	OperationTwo operation
+	Position     int
+	Length       int
 	Metadata     operationMetadata
 }
 
@@ -88,6 +99,134 @@ func newAndOperation(dataType operationDataType, operationOne operation, operati
 	}
 }
 
+// BitwiseAnd
+type bitwiseAndOperation struct {
+	OperationOne operation
+	OperationTwo operation
+	Position     int
+	Length       int
+	Metadata     operationMetadata
+}
+
+func (op *bitwiseAndOperation) OperationMetadata() operationMetadata { return op.Metadata }
+
+func newBitwiseAndOperation(dataType operationDataType, operationOne operation, operationTwo operation) *bitwiseAndOperation {
+
+	meta := operationMetadata{
+		DataType:           dataType,
+		DependsOnVariables: operationOne.OperationMetadata().DependsOnVariables || operationTwo.OperationMetadata().DependsOnVariables,
+		IsIdempotent:       operationOne.OperationMetadata().IsIdempotent && operationTwo.OperationMetadata().IsIdempotent,
+	}
+
+	return &bitwiseAndOperation{
+		OperationOne: operationOne,
+		OperationTwo: operationTwo,
+		Metadata:     meta,
+	}
+}
+
+// BitwiseNot
+type bitwiseNotOperation struct {
+	Operation operation
+	Position  int
+	Length    int
+	Metadata  operationMetadata
+}
+
+func (op *bitwiseNotOperation) OperationMetadata() operationMetadata { return op.Metadata }
+
+func newBitwiseNotOperation(dataType operationDataType, operation operation) *bitwiseNotOperation {
+
+	meta := operationMetadata{
+		DataType:           dataType,
+		DependsOnVariables: operation.OperationMetadata().DependsOnVariables,
+		IsIdempotent:       operation.OperationMetadata().IsIdempotent,
+	}
+
+	return &bitwiseNotOperation{
+		Operation: operation,
+		Metadata:  meta,
+	}
+}
+
+// BitwiseOr
+type bitwiseOrOperation struct {
+	OperationOne operation
+	OperationTwo operation
+	Position     int
+	Length       int
+	Metadata     operationMetadata
+}
+
+func (op *bitwiseOrOperation) OperationMetadata() operationMetadata { return op.Metadata }
+
+func newBitwiseOrOperation(dataType operationDataType, operationOne operation, operationTwo operation) *bitwiseOrOperation {
+
+	meta := operationMetadata{
+		DataType:           dataType,
+		DependsOnVariables: operationOne.OperationMetadata().DependsOnVariables || operationTwo.OperationMetadata().DependsOnVariables,
+		IsIdempotent:       operationOne.OperationMetadata().IsIdempotent && operationTwo.OperationMetadata().IsIdempotent,
+	}
+
+	return &bitwiseOrOperation{
+		OperationOne: operationOne,
+		OperationTwo: operationTwo,
+		Metadata:     meta,
+	}
+}
+
+// BitwiseXor
+type bitwiseXorOperation struct {
+	OperationOne operation
+	OperationTwo operation
+	Position     int
+	Length       int
+	Metadata     operationMetadata
+}
+
+func (op *bitwiseXorOperation) OperationMetadata() operationMetadata { return op.Metadata }
+
+func newBitwiseXorOperation(dataType operationDataType, operationOne operation, operationTwo operation) *bitwiseXorOperation {
+
+	meta := operationMetadata{
+		DataType:           dataType,
+		DependsOnVariables: operationOne.OperationMetadata().DependsOnVariables || operationTwo.OperationMetadata().DependsOnVariables,
+		IsIdempotent:       operationOne.OperationMetadata().IsIdempotent && operationTwo.OperationMetadata().IsIdempotent,
+	}
+
+	return &bitwiseXorOperation{
+		OperationOne: operationOne,
+		OperationTwo: operationTwo,
+		Metadata:     meta,
+	}
+}
+
+// Concatenation
+type concatOperation struct {
+	OperationOne operation
+	OperationTwo operation
+	Position     int
+	Length       int
+	Metadata     operationMetadata
+}
+
+func (op *concatOperation) OperationMetadata() operationMetadata { return op.Metadata }
+
+func newConcatOperation(operationOne operation, operationTwo operation) *concatOperation {
+
+	meta := operationMetadata{
+		DataType:           text,
+		DependsOnVariables: operationOne.OperationMetadata().DependsOnVariables || operationTwo.OperationMetadata().DependsOnVariables,
+		IsIdempotent:       operationOne.OperationMetadata().IsIdempotent && operationTwo.OperationMetadata().IsIdempotent,
+	}
+
+	return &concatOperation{
+		OperationOne: operationOne,
+		OperationTwo: operationTwo,
+		Metadata:     meta,
+	}
+}
+
 // Constant
 type constantOperation struct {
 	Value    interface{}
@@ -96,6 +235,11 @@ type constantOperation struct {
 
 func (op *constantOperation) OperationMetadata() operationMetadata { return op.Metadata }
 
+// Tells whether the constant holds a number.
+func (op *constantOperation) isNumber() bool {
+	return op.Metadata.DataType == integer || op.Metadata.DataType == floatingPoint
+}
+
 func newConstantOperation(dataType operationDataType, value interface{}) *constantOperation {
 	meta := operationMetadata{
 		DataType:           dataType,
@@ -113,6 +257,8 @@ func newConstantOperation(dataType operationDataType, value interface{}) *consta
 type divisorOperation struct {
 	Dividend operation
 	Divisor  operation
+	Position int
+	Length   int
 	Metadata operationMetadata
 }
 
@@ -137,6 +283,8 @@ func newDivisorOperation(dataType operationDataType, dividend operation, divisor
 type equalOperation struct {
 	OperationOne operation
 	OperationTwo operation
+	Position     int
+	Length       int
 	Metadata     operationMetadata
 }
 
@@ -161,6 +309,8 @@ func newEqualOperation(dataType operationDataType, operationOne operation, opera
 type exponentiationOperation struct {
 	Base     operation
 	Exponent operation
+	Position int
+	Length   int
 	Metadata operationMetadata
 }
 
@@ -181,10 +331,36 @@ func newExponentiationOperation(dataType operationDataType, base operation, expo
 	}
 }
 
+// Factorial
+type factorialOperation struct {
+	Operation operation
+	Position  int
+	Length    int
+	Metadata  operationMetadata
+}
+
+func (op *factorialOperation) OperationMetadata() operationMetadata { return op.Metadata }
+
+func newFactorialOperation(dataType operationDataType, operation operation) *factorialOperation {
+
+	meta := operationMetadata{
+		DataType:           dataType,
+		DependsOnVariables: operation.OperationMetadata().DependsOnVariables,
+		IsIdempotent:       operation.OperationMetadata().IsIdempotent,
+	}
+
+	return &factorialOperation{
+		Operation: operation,
+		Metadata:  meta,
+	}
+}
+
 // Function
 type functionOperation struct {
 	Name      string
 	Arguments []operation
+	Position  int
+	Length    int
 	Metadata  operationMetadata
 }
 
@@ -216,6 +392,8 @@ func newFunctionOperation(dataType operationDataType, name string, arguments []o
 type greaterOrEqualThanOperation struct {
 	OperationOne operation
 	OperationTwo operation
+	Position     int
+	Length       int
 	Metadata     operationMetadata
 }
 
@@ -240,6 +418,8 @@ func newGreaterOrEqualThanOperation(dataType operationDataType, operationOne ope
 type greaterThanOperation struct {
 	OperationOne operation
 	OperationTwo operation
+	Position     int
+	Length       int
 	Metadata     operationMetadata
 }
 
@@ -260,10 +440,64 @@ func newGreaterThanOperation(dataType operationDataType, operationOne operation,
 	}
 }
 
+// IntegerDivision
+type integerDivisionOperation struct {
+	Dividend operation
+	Divisor  operation
+	Position int
+	Length   int
+	Metadata operationMetadata
+}
+
+func (op *integerDivisionOperation) OperationMetadata() operationMetadata { return op.Metadata }
+
+func newIntegerDivisionOperation(dataType operationDataType, dividend operation, divisor operation) *integerDivisionOperation {
+
+	meta := operationMetadata{
+		DataType:           dataType,
+		DependsOnVariables: dividend.OperationMetadata().DependsOnVariables || divisor.OperationMetadata().DependsOnVariables,
+		IsIdempotent:       dividend.OperationMetadata().IsIdempotent && divisor.OperationMetadata().IsIdempotent,
+	}
+
+	return &integerDivisionOperation{
+		Dividend: dividend,
+		Divisor:  divisor,
+		Metadata: meta,
+	}
+}
+
+// LeftShift
+type leftShiftOperation struct {
+	OperationOne operation
+	OperationTwo operation
+	Position     int
+	Length       int
+	Metadata     operationMetadata
+}
+
+func (op *leftShiftOperation) OperationMetadata() operationMetadata { return op.Metadata }
+
+func newLeftShiftOperation(dataType operationDataType, operationOne operation, operationTwo operation) *leftShiftOperation {
+
+	meta := operationMetadata{
+		DataType:           dataType,
+		DependsOnVariables: operationOne.OperationMetadata().DependsOnVariables || operationTwo.OperationMetadata().DependsOnVariables,
+		IsIdempotent:       operationOne.OperationMetadata().IsIdempotent && operationTwo.OperationMetadata().IsIdempotent,
+	}
+
+	return &leftShiftOperation{
+		OperationOne: operationOne,
+		OperationTwo: operationTwo,
+		Metadata:     meta,
+	}
+}
+
 // LessOrEqualThan
 type lessOrEqualThanOperation struct {
 	OperationOne operation
 	OperationTwo operation
+	Position     int
+	Length       int
 	Metadata     operationMetadata
 }
 
@@ -288,6 +522,8 @@ func newLessOrEqualThanOperation(dataType operationDataType, operationOne operat
 type lessThanOperation struct {
 	OperationOne operation
 	OperationTwo operation
+	Position     int
+	Length       int
 	Metadata     operationMetadata
 }
 
@@ -312,6 +548,8 @@ func newLessThanOperation(dataType operationDataType, operationOne operation, op
 type moduloOperation struct {
 	Dividend operation
 	Divisor  operation
+	Position int
+	Length   int
 	Metadata operationMetadata
 }
 
@@ -336,6 +574,8 @@ func newModuloOperation(dataType operationDataType, dividend operation, divisor
 type multiplicationOperation struct {
 	OperationOne operation
 	OperationTwo operation
+	Position     int
+	Length       int
 	Metadata     operationMetadata
 }
 
@@ -360,6 +600,8 @@ func newMultiplicationOperation(dataType operationDataType, operationOne operati
 type notEqualOperation struct {
 	OperationOne operation
 	OperationTwo operation
+	Position     int
+	Length       int
 	Metadata     operationMetadata
 }
 
@@ -384,6 +626,8 @@ func newNotEqualOperation(dataType operationDataType, operationOne operation, op
 type orOperation struct {
 	OperationOne operation
 	OperationTwo operation
+	Position     int
+	Length       int
 	Metadata     operationMetadata
 }
 
@@ -404,10 +648,62 @@ func newOrOperation(dataType operationDataType, operationOne operation, operatio
 	}
 }
 
+// Percentage
+type percentageOperation struct {
+	Operation operation
+	Position  int
+	Length    int
+	Metadata  operationMetadata
+}
+
+func (op *percentageOperation) OperationMetadata() operationMetadata { return op.Metadata }
+
+func newPercentageOperation(dataType operationDataType, operation operation) *percentageOperation {
+
+	meta := operationMetadata{
+		DataType:           dataType,
+		DependsOnVariables: operation.OperationMetadata().DependsOnVariables,
+		IsIdempotent:       operation.OperationMetadata().IsIdempotent,
+	}
+
+	return &percentageOperation{
+		Operation: operation,
+		Metadata:  meta,
+	}
+}
+
+// RightShift
+type rightShiftOperation struct {
+	OperationOne operation
+	OperationTwo operation
+	Position     int
+	Length       int
+	Metadata     operationMetadata
+}
+
+func (op *rightShiftOperation) OperationMetadata() operationMetadata { return op.Metadata }
+
+func newRightShiftOperation(dataType operationDataType, operationOne operation, operationTwo operation) *rightShiftOperation {
+
+	meta := operationMetadata{
+		DataType:           dataType,
+		DependsOnVariables: operationOne.OperationMetadata().DependsOnVariables || operationTwo.OperationMetadata().DependsOnVariables,
+		IsIdempotent:       operationOne.OperationMetadata().IsIdempotent && operationTwo.OperationMetadata().IsIdempotent,
+	}
+
+	return &rightShiftOperation{
+		OperationOne: operationOne,
+		OperationTwo: operationTwo,
+		Metadata:     meta,
+	}
+}
+
 //Subtraction
 type subtractionOperation struct {
 	OperationOne operation
 	OperationTwo operation
+	Position     int
+	Length       int
 	Metadata     operationMetadata
 }
 
@@ -431,6 +727,8 @@ func newSubtractionOperation(dataType operationDataType, operationOne operation,
 //UnaryMinus
 type unaryMinusOperation struct {
 	Operation operation
+	Position  int
+	Length    int
 	Metadata  operationMetadata
 }
 
@@ -10,8 +10,12 @@ func (this *optimizer) optimize(op operation, functionRegistry *functionRegistry
 func optimize(executor interpreter, op operation, functionRegistry *functionRegistry, constantRegistry *constantRegistry) operation {
 
 	if _, b := op.(*constantOperation); !op.OperationMetadata().DependsOnVariables && op.OperationMetadata().IsIdempotent && !b {
-		result, _ := executor.execute(op, nil, functionRegistry, constantRegistry)
-		return newConstantOperation(floatingPoint, result)
+		result, err := executor.evaluate(op, nil, functionRegistry, constantRegistry)
+		if err != nil || result.kind != KindNumber {
+			// keep the operation, so the error is reported when the formula is evaluated
+			return op
+		}
+		return newConstantOperation(floatingPoint, result.number)
 	} else {
 
 		if cop, ok := op.(*addOperation); ok {
@@ -25,7 +29,7 @@ func optimize(executor interpreter, op operation, functionRegistry *functionRegi
 		} else if cop, ok := op.(*multiplicationOperation); ok {
 			cop.OperationOne = optimize(executor, cop.OperationOne, functionRegistry, constantRegistry)
 			cop1, ok1 := cop.OperationOne.(*constantOperation)
-			if ok1 {
+			if ok1 && cop1.isNumber() {
 				if cop1.Metadata.DataType == floatingPoint && cop1.Value == 0.0 {
 					return newConstantOperation(floatingPoint, 0.0)
 				} else {
@@ -37,7 +41,7 @@ func optimize(executor interpreter, op operation, functionRegistry *functionRegi
 
 			cop.OperationTwo = optimize(executor, cop.OperationTwo, functionRegistry, constantRegistry)
 			cop2, ok2 := cop.OperationTwo.(*constantOperation)
-			if ok2 {
+			if ok2 && cop2.isNumber() {
 				if cop2.Metadata.DataType == floatingPoint && cop2.Value == 0.0 {
 					return newConstantOperation(floatingPoint, 0.0)
 				} else {
@@ -67,7 +71,7 @@ func optimize(executor interpreter, op operation, functionRegistry *functionRegi
 
 			cop.OperationOne = optimize(executor, cop.OperationOne, functionRegistry, constantRegistry)
 			cop1, ok1 := cop.OperationOne.(*constantOperation)
-			if ok1 {
+			if ok1 && cop1.isNumber() {
 				if cop1.Metadata.DataType == floatingPoint && cop1.Value == 0.0 {
 					return newConstantOperation(floatingPoint, 0.0)
 				} else {
@@ -79,7 +83,7 @@ func optimize(executor interpreter, op operation, functionRegistry *functionRegi
 
 			cop.OperationTwo = optimize(executor, cop.OperationTwo, functionRegistry, constantRegistry)
 			cop2, ok2 := cop.OperationTwo.(*constantOperation)
-			if ok2 {
+			if ok2 && cop2.isNumber() {
 				if cop2.Metadata.DataType == floatingPoint && cop2.Value == 0.0 {
 					return newConstantOperation(floatingPoint, 0.0)
 				} else {
@@ -92,7 +96,7 @@ func optimize(executor interpreter, op operation, functionRegistry *functionRegi
 		} else if cop, ok := op.(*orOperation); ok {
 			cop.OperationOne = optimize(executor, cop.OperationOne, functionRegistry, constantRegistry)
 			cop1, ok1 := cop.OperationOne.(*constantOperation)
-			if ok1 {
+			if ok1 && cop1.isNumber() {
 				if cop1.Metadata.DataType == floatingPoint && cop1.Value == 1.0 {
 					return newConstantOperation(floatingPoint, 1.0)
 				} else {
@@ -104,7 +108,7 @@ func optimize(executor interpreter, op operation, functionRegistry *functionRegi
 
 			cop.OperationTwo = optimize(executor, cop.OperationTwo, functionRegistry, constantRegistry)
 			cop2, ok2 := cop.OperationTwo.(*constantOperation)
-			if ok2 {
+			if ok2 && cop2.isNumber() {
 				if cop2.Metadata.DataType == floatingPoint && cop2.Value == 1.0 {
 					return newConstantOperation(floatingPoint, 1.0)
 				} else {
@@ -0,0 +1,115 @@
+package gojacego
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+/*
+	A random number generator that can be shared by concurrent evaluations, unlike a rand.Rand.
+*/
+type randomGenerator struct {
+	mutex sync.Mutex
+	rand  *rand.Rand
+}
+
+func newRandomGenerator(source rand.Source) *randomGenerator {
+	return &randomGenerator{rand: rand.New(source)}
+}
+
+func (this *randomGenerator) float64() float64 {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	return this.rand.Float64()
+}
+
+func (this *randomGenerator) int63n(n int64) int64 {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	return this.rand.Int63n(n)
+}
+
+func (this *randomGenerator) normFloat64() float64 {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	return this.rand.NormFloat64()
+}
+
+type randomSeedKey struct{}
+
+type randomGeneratorKey struct{}
+
+/*
+	Returns a copy of [ctx] that makes the random functions reproducible: every evaluation given
+	this context (i.e. by 'CalculationEngine.CalculateContext') draws its numbers from a new source
+	seeded with [seed], so it returns the same result each time.
+*/
+func ContextWithRandomSeed(ctx context.Context, seed int64) context.Context {
+	return context.WithValue(ctx, randomSeedKey{}, seed)
+}
+
+// Gives the evaluation its own generator when its context carries a seed.
+func withEvaluationRandom(ctx context.Context) context.Context {
+	seed, ok := ctx.Value(randomSeedKey{}).(int64)
+	if !ok {
+		return ctx
+	}
+	return context.WithValue(ctx, randomGeneratorKey{}, newRandomGenerator(rand.NewSource(seed)))
+}
+
+/*
+	Registers the functions returning random numbers. They draw from the generator of the evaluation
+	when its context is seeded, otherwise from the one of the engine.
+*/
+func registryRandomFunctions(registry *functionRegistry, options *jaceOptions) {
+	engineGenerator := options.random
+	if engineGenerator == nil {
+		engineGenerator = newRandomGenerator(rand.NewSource(time.Now().UnixNano()))
+	}
+
+	generator := func(ctx context.Context) *randomGenerator {
+		if evaluationGenerator, ok := ctx.Value(randomGeneratorKey{}).(*randomGenerator); ok {
+			return evaluationGenerator
+		}
+		return engineGenerator
+	}
+
+	// the argument, which used to be the seed, is accepted for compatibility and ignored
+	registry.registerContextFunction("random", func(ctx context.Context, arguments []float64) (float64, error) {
+		return generator(ctx).float64(), nil
+	}, 0, 1, false, false)
+
+	// randint(a, b), an integer between a and b, both included
+	registry.registerContextFunction("randint", func(ctx context.Context, arguments []float64) (float64, error) {
+		low, high := arguments[0], arguments[1]
+		if low != math.Trunc(low) || high != math.Trunc(high) || math.Abs(low) > maxExactInteger || math.Abs(high) > maxExactInteger {
+			return 0, fmt.Errorf("the bounds must be integers between -2^53 and 2^53, got %v and %v", low, high)
+		}
+		if low > high {
+			return 0, fmt.Errorf("the lower bound %v is greater than the upper bound %v", low, high)
+		}
+		return low + float64(generator(ctx).int63n(int64(high-low)+1)), nil
+	}, 2, 2, true, false)
+
+	// randn([mu [, sigma]]), normally distributed with the mean mu (0) and the standard deviation sigma (1)
+	registry.registerContextFunction("randn", func(ctx context.Context, arguments []float64) (float64, error) {
+		mu, sigma := optionalArgument(arguments, 0, 0), optionalArgument(arguments, 1, 1)
+		if !(sigma >= 0) {
+			return 0, fmt.Errorf("the standard deviation cannot be negative, got %v", sigma)
+		}
+		return mu + sigma*generator(ctx).normFloat64(), nil
+	}, 0, 2, true, false)
+
+	// choice(x1, ..., xn), one of the arguments
+	registry.registerContextFunction("choice", func(ctx context.Context, arguments []float64) (float64, error) {
+		if len(arguments) == 0 {
+			return 0, errors.New("expected at least one value to choose from")
+		}
+		return arguments[generator(ctx).int63n(int64(len(arguments)))], nil
+	}, 1, unlimitedParameters, true, false)
+}
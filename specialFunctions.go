@@ -0,0 +1,177 @@
+package gojacego
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+/*
+	ErrOutOfDomain is returned, wrapped in a *FunctionError, by the functions of the function packs
+	that are called with arguments for which they are not defined (i.e. 'fact(-1)').
+*/
+var ErrOutOfDomain = errors.New("argument out of domain")
+
+/*
+	Registers the functions of the SpecialMathPack.
+*/
+func registrySpecialFunctions(registry *functionRegistry, options *jaceOptions) {
+
+	registry.registerTypedFunction("gamma", func(arguments []float64) (float64, error) {
+		if err := checkNotPole(arguments[0]); err != nil {
+			return 0, err
+		}
+		return math.Gamma(arguments[0]), nil
+	}, 1, 1, false, true)
+
+	// the logarithm of the absolute value of gamma
+	registry.registerTypedFunction("lgamma", func(arguments []float64) (float64, error) {
+		if err := checkNotPole(arguments[0]); err != nil {
+			return 0, err
+		}
+		lgamma, _ := math.Lgamma(arguments[0])
+		return lgamma, nil
+	}, 1, 1, false, true)
+
+	registry.registerTypedFunction("beta", func(arguments []float64) (float64, error) {
+		if !(arguments[0] > 0 && arguments[1] > 0) {
+			return 0, domainError("the arguments must be positive, got %v and %v", arguments[0], arguments[1])
+		}
+		lgammaA, _ := math.Lgamma(arguments[0])
+		lgammaB, _ := math.Lgamma(arguments[1])
+		lgammaAB, _ := math.Lgamma(arguments[0] + arguments[1])
+		return math.Exp(lgammaA + lgammaB - lgammaAB), nil
+	}, 2, 2, false, true)
+
+	registry.registerTypedFunction("erf", func(arguments []float64) (float64, error) {
+		return math.Erf(arguments[0]), nil
+	}, 1, 1, false, true)
+
+	registry.registerTypedFunction("erfc", func(arguments []float64) (float64, error) {
+		return math.Erfc(arguments[0]), nil
+	}, 1, 1, false, true)
+
+	registry.registerTypedFunction("erfinv", func(arguments []float64) (float64, error) {
+		if !(arguments[0] > -1 && arguments[0] < 1) {
+			return 0, domainError("the argument must be between -1 and 1 excluded, got %v", arguments[0])
+		}
+		return math.Erfinv(arguments[0]), nil
+	}, 1, 1, false, true)
+
+	registry.registerTypedFunction("j0", func(arguments []float64) (float64, error) {
+		return math.J0(arguments[0]), nil
+	}, 1, 1, false, true)
+
+	registry.registerTypedFunction("j1", func(arguments []float64) (float64, error) {
+		return math.J1(arguments[0]), nil
+	}, 1, 1, false, true)
+
+	// jn(n, x)
+	registry.registerTypedFunction("jn", func(arguments []float64) (float64, error) {
+		order, err := besselOrder(arguments[0])
+		if err != nil {
+			return 0, err
+		}
+		return math.Jn(order, arguments[1]), nil
+	}, 2, 2, false, true)
+
+	registry.registerTypedFunction("y0", func(arguments []float64) (float64, error) {
+		if err := checkPositive(arguments[0]); err != nil {
+			return 0, err
+		}
+		return math.Y0(arguments[0]), nil
+	}, 1, 1, false, true)
+
+	registry.registerTypedFunction("y1", func(arguments []float64) (float64, error) {
+		if err := checkPositive(arguments[0]); err != nil {
+			return 0, err
+		}
+		return math.Y1(arguments[0]), nil
+	}, 1, 1, false, true)
+
+	// yn(n, x)
+	registry.registerTypedFunction("yn", func(arguments []float64) (float64, error) {
+		order, err := besselOrder(arguments[0])
+		if err != nil {
+			return 0, err
+		}
+		if err := checkPositive(arguments[1]); err != nil {
+			return 0, err
+		}
+		return math.Yn(order, arguments[1]), nil
+	}, 2, 2, false, true)
+
+	registry.registerTypedFunction("expm1", func(arguments []float64) (float64, error) {
+		return math.Expm1(arguments[0]), nil
+	}, 1, 1, false, true)
+
+	registry.registerTypedFunction("log1p", func(arguments []float64) (float64, error) {
+		if !(arguments[0] > -1) {
+			return 0, domainError("the argument must be greater than -1, got %v", arguments[0])
+		}
+		return math.Log1p(arguments[0]), nil
+	}, 1, 1, false, true)
+
+	registry.registerTypedFunction("hypot", func(arguments []float64) (float64, error) {
+		return math.Hypot(arguments[0], arguments[1]), nil
+	}, 2, 2, false, true)
+
+	// atan2(y, x), an angle in the unit chosen with 'WithAngleUnit'
+	registry.registerTypedFunction("atan2", func(arguments []float64) (float64, error) {
+		return options.angleUnit.fromRadians(math.Atan2(arguments[0], arguments[1])), nil
+	}, 2, 2, false, true)
+
+	registry.registerTypedFunction("cbrt", func(arguments []float64) (float64, error) {
+		return math.Cbrt(arguments[0]), nil
+	}, 1, 1, false, true)
+
+	// the IntegerPack replaces it by its exact factorial when both packs are enabled
+	registry.registerTypedFunction("fact", func(arguments []float64) (float64, error) {
+		return floatFactorial(arguments[0])
+	}, 1, 1, true, true)
+}
+
+// The largest number whose factorial is a finite float64.
+const maxFactorial = 170
+
+// The factorial of a non-negative integer up to 170, computed on float64.
+func floatFactorial(n float64) (float64, error) {
+	if n < 0 || n != math.Trunc(n) {
+		return 0, domainError("the argument must be a non-negative integer, got %v", n)
+	}
+	if n > maxFactorial {
+		return 0, domainError("the factorial of %v overflows float64, the maximum is %d", n, maxFactorial)
+	}
+
+	result := 1.0
+	for i := 2.0; i <= n; i++ {
+		result *= i
+	}
+	return result, nil
+}
+
+func domainError(format string, arguments ...interface{}) error {
+	return fmt.Errorf("%w: %s", ErrOutOfDomain, fmt.Sprintf(format, arguments...))
+}
+
+// The gamma function is not defined for zero and the negative integers.
+func checkNotPole(x float64) error {
+	if x <= 0 && x == math.Trunc(x) {
+		return domainError("the argument cannot be zero or a negative integer, got %v", x)
+	}
+	return nil
+}
+
+func checkPositive(x float64) error {
+	if !(x > 0) {
+		return domainError("the argument must be positive, got %v", x)
+	}
+	return nil
+}
+
+func besselOrder(n float64) (int, error) {
+	if n != math.Trunc(n) || math.Abs(n) > math.MaxInt32 {
+		return 0, domainError("the order must be an integer, got %v", n)
+	}
+	return int(n), nil
+}
@@ -0,0 +1,220 @@
+package gojacego
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+)
+
+/*
+	ErrNotEnoughValues is returned, wrapped in a *FunctionError, by the functions of the function
+	packs that are called with too few values (i.e. 'avg()' or 'stdev' of a single value).
+*/
+var ErrNotEnoughValues = errors.New("not enough values")
+
+/*
+	Registers the functions of the StatisticsPack. They take a series of values: 'percentile' and
+	'quantile' take their rank first, and 'correl' takes the values of both series one after the other.
+*/
+func registryStatisticsFunctions(registry *functionRegistry, options *jaceOptions) {
+
+	// avg and median are default functions too
+	registry.registerTypedFunction("avg", averageFunction, 0, unlimitedParameters, true, true)
+	registry.registerTypedFunction("median", medianFunction, 0, unlimitedParameters, true, true)
+
+	// the smallest of the most frequent values
+	registry.registerTypedFunction("mode", func(arguments []float64) (float64, error) {
+		if err := requireValues(arguments, 1); err != nil {
+			return 0, err
+		}
+
+		sorted := sortedCopy(arguments)
+		mode, modeCount := sorted[0], 0
+		for start := 0; start < len(sorted); {
+			end := start + 1
+			for end < len(sorted) && sorted[end] == sorted[start] {
+				end++
+			}
+			if end-start > modeCount {
+				mode, modeCount = sorted[start], end-start
+			}
+			start = end
+		}
+		return mode, nil
+	}, 0, unlimitedParameters, false, true)
+
+	registry.registerTypedFunction("var", func(arguments []float64) (float64, error) {
+		if err := requireValues(arguments, 2); err != nil {
+			return 0, err
+		}
+		return sumOfSquaredDeviations(arguments) / float64(len(arguments)-1), nil
+	}, 0, unlimitedParameters, false, true)
+
+	registry.registerTypedFunction("varp", func(arguments []float64) (float64, error) {
+		if err := requireValues(arguments, 1); err != nil {
+			return 0, err
+		}
+		return sumOfSquaredDeviations(arguments) / float64(len(arguments)), nil
+	}, 0, unlimitedParameters, false, true)
+
+	registry.registerTypedFunction("stdev", func(arguments []float64) (float64, error) {
+		if err := requireValues(arguments, 2); err != nil {
+			return 0, err
+		}
+		return math.Sqrt(sumOfSquaredDeviations(arguments) / float64(len(arguments)-1)), nil
+	}, 0, unlimitedParameters, false, true)
+
+	registry.registerTypedFunction("stdevp", func(arguments []float64) (float64, error) {
+		if err := requireValues(arguments, 1); err != nil {
+			return 0, err
+		}
+		return math.Sqrt(sumOfSquaredDeviations(arguments) / float64(len(arguments))), nil
+	}, 0, unlimitedParameters, false, true)
+
+	// percentile(p, x1, ..., xn) with 'p' between 0 and 100
+	registry.registerTypedFunction("percentile", func(arguments []float64) (float64, error) {
+		if err := requireValues(arguments[1:], 1); err != nil {
+			return 0, err
+		}
+		if !(arguments[0] >= 0 && arguments[0] <= 100) {
+			return 0, fmt.Errorf("the percentile must be between 0 and 100, got %v", arguments[0])
+		}
+		return quantile(sortedCopy(arguments[1:]), arguments[0]/100), nil
+	}, 1, unlimitedParameters, false, true)
+
+	// quantile(q, x1, ..., xn) with 'q' between 0 and 1
+	registry.registerTypedFunction("quantile", func(arguments []float64) (float64, error) {
+		if err := requireValues(arguments[1:], 1); err != nil {
+			return 0, err
+		}
+		if !(arguments[0] >= 0 && arguments[0] <= 1) {
+			return 0, fmt.Errorf("the quantile must be between 0 and 1, got %v", arguments[0])
+		}
+		return quantile(sortedCopy(arguments[1:]), arguments[0]), nil
+	}, 1, unlimitedParameters, false, true)
+
+	registry.registerTypedFunction("geomean", func(arguments []float64) (float64, error) {
+		if err := requireValues(arguments, 1); err != nil {
+			return 0, err
+		}
+
+		sumOfLogs := 0.0
+		for _, value := range arguments {
+			if value <= 0 {
+				return 0, fmt.Errorf("the values must be positive, got %v", value)
+			}
+			sumOfLogs += math.Log(value)
+		}
+		return math.Exp(sumOfLogs / float64(len(arguments))), nil
+	}, 0, unlimitedParameters, false, true)
+
+	registry.registerTypedFunction("harmean", func(arguments []float64) (float64, error) {
+		if err := requireValues(arguments, 1); err != nil {
+			return 0, err
+		}
+
+		sumOfInverses := 0.0
+		for _, value := range arguments {
+			if value <= 0 {
+				return 0, fmt.Errorf("the values must be positive, got %v", value)
+			}
+			sumOfInverses += 1 / value
+		}
+		return float64(len(arguments)) / sumOfInverses, nil
+	}, 0, unlimitedParameters, false, true)
+
+	registry.registerTypedFunction("sumsq", func(arguments []float64) (float64, error) {
+		if err := requireValues(arguments, 1); err != nil {
+			return 0, err
+		}
+
+		sum := 0.0
+		for _, value := range arguments {
+			sum += value * value
+		}
+		return sum, nil
+	}, 0, unlimitedParameters, false, true)
+
+	// correl(x1, ..., xn, y1, ..., yn)
+	registry.registerTypedFunction("correl", func(arguments []float64) (float64, error) {
+		if len(arguments)%2 != 0 {
+			return 0, fmt.Errorf("expected two series of the same length, got %d values", len(arguments))
+		}
+		if err := requireValues(arguments, 4); err != nil {
+			return 0, err
+		}
+
+		x, y := arguments[:len(arguments)/2], arguments[len(arguments)/2:]
+		meanX, meanY := mean(x), mean(y)
+
+		covariance, varianceX, varianceY := 0.0, 0.0, 0.0
+		for idx := range x {
+			dx, dy := x[idx]-meanX, y[idx]-meanY
+			covariance += dx * dy
+			varianceX += dx * dx
+			varianceY += dy * dy
+		}
+
+		if varianceX == 0 || varianceY == 0 {
+			return 0, errors.New("the correlation of a constant series is undefined")
+		}
+		return covariance / math.Sqrt(varianceX*varianceY), nil
+	}, 0, unlimitedParameters, false, true)
+}
+
+func averageFunction(arguments []float64) (float64, error) {
+	if err := requireValues(arguments, 1); err != nil {
+		return 0, err
+	}
+	return mean(arguments), nil
+}
+
+func medianFunction(arguments []float64) (float64, error) {
+	if err := requireValues(arguments, 1); err != nil {
+		return 0, err
+	}
+	return quantile(sortedCopy(arguments), 0.5), nil
+}
+
+func requireValues(values []float64, minimum int) error {
+	if len(values) < minimum {
+		return fmt.Errorf("%w: expected at least %d, got %d", ErrNotEnoughValues, minimum, len(values))
+	}
+	return nil
+}
+
+func mean(values []float64) float64 {
+	sum := 0.0
+	for _, value := range values {
+		sum += value
+	}
+	return sum / float64(len(values))
+}
+
+func sumOfSquaredDeviations(values []float64) float64 {
+	average := mean(values)
+
+	sum := 0.0
+	for _, value := range values {
+		sum += (value - average) * (value - average)
+	}
+	return sum
+}
+
+func sortedCopy(values []float64) []float64 {
+	sorted := make([]float64, len(values))
+	copy(sorted, values)
+	sort.Float64s(sorted)
+	return sorted
+}
+
+// Returns the quantile [q] of the sorted values, interpolating linearly between the closest ranks.
+func quantile(sorted []float64, q float64) float64 {
+	rank := q * float64(len(sorted)-1)
+	lower := int(math.Floor(rank))
+	if lower+1 >= len(sorted) {
+		return sorted[lower]
+	}
+	return sorted[lower] + (rank-float64(lower))*(sorted[lower+1]-sorted[lower])
+}
@@ -1,5 +1,11 @@
 package gojacego
 
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
 /*
 	Represents an input token
 */
@@ -9,3 +15,47 @@ type token struct {
 	Type          tokenType
 	Value         interface{}
 }
+
+// Returns the text of the token as it is written in a formula.
+func tokenText(t token) string {
+	switch value := t.Value.(type) {
+	case string:
+		if t.Type == tt_STRING {
+			return `"` + strings.ReplaceAll(value, `"`, `""`) + `"`
+		}
+		return value
+	case time.Time:
+		return "#" + value.Format(time.RFC3339Nano) + "#"
+	case rune:
+		switch value {
+		case '_':
+			return "-"
+		case '％':
+			return "%"
+		case '≤':
+			return "<="
+		case '≥':
+			return ">="
+		case '≠':
+			return "!="
+		case '∧':
+			return "&&"
+		case '∨':
+			return "||"
+		case '⊕':
+			return "xor"
+		case '≪':
+			return "<<"
+		case '≫':
+			return ">>"
+		case '⫽':
+			return "//"
+		case '=':
+			return "=="
+		case '⧺':
+			return "&"
+		}
+		return string(value)
+	}
+	return fmt.Sprint(t.Value)
+}
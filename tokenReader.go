@@ -4,11 +4,20 @@ import (
 	"errors"
 	"fmt"
 	"strconv"
+	"strings"
+	"time"
+	"unicode"
 )
 
 type tokenReader struct {
 	decimalSeparator  rune
 	argumentSeparator rune
+	maxFormulaLength  int
+	maxTokens         int
+	dialect           Dialect
+	angleUnit         AngleUnit
+	units             bool
+	caseSensitive     bool
 }
 
 func newTokenReader(decimalSeparator rune, argumentSeparador rune) *tokenReader {
@@ -19,10 +28,32 @@ func newTokenReader(decimalSeparator rune, argumentSeparador rune) *tokenReader
 }
 
 func (this tokenReader) read(formula string) ([]token, error) {
+	tokens, errs := this.readAll(formula)
+	if len(errs) > 0 {
+		return nil, errs[0]
+	}
+	return tokens, nil
+}
+
+/*
+	Read the tokens of the formula, recovering from invalid characters and numbers: they are reported
+	and skipped, so every one of them is found in a single pass. Exceeding a limit stops the reading.
+*/
+func (this tokenReader) readAll(formula string) ([]token, []error) {
 	ret := make([]token, 0)
+	var errs []error
+
+	// the invalid parts are kept as tokens, so the parser knows where to resume after them
+	addInvalidToken := func(err *SyntaxError) {
+		errs = append(errs, err)
+		ret = append(ret, token{Type: tt_INVALID,
+			Value:         err.Token,
+			StartPosition: err.Position,
+			Length:        err.Length})
+	}
 
 	if formula == "" {
-		return nil, errors.New("formula cannot be empty")
+		return nil, []error{&SyntaxError{Code: ErrorCodeEmptyFormula, Message: "formula cannot be empty"}}
 	}
 
 	runes := []rune(formula)
@@ -30,20 +61,95 @@ func (this tokenReader) read(formula string) ([]token, error) {
 	isFormulaSubPart := true
 	isScientific := false
 
-	for i := 0; i < runesLength; i++ {
+	if this.maxFormulaLength > 0 && runesLength > this.maxFormulaLength {
+		return nil, []error{&MaxFormulaLengthError{Length: runesLength, Limit: this.maxFormulaLength}}
+	}
+
+	// reads what follows the number starting at [startPosition] (a '°', a factorial or a unit) and
+	// returns where the reading resumes
+	readAfterNumber := func(i int, startPosition int) int {
+		last := len(ret) - 1
+		if last < 0 || ret[last].StartPosition != startPosition || (ret[last].Type != tt_INTEGER && ret[last].Type != tt_FLOATING_POINT) {
+			return i
+		}
+
+		if i < runesLength && runes[i] == '°' {
+			ret[last] = this.degreeToken(ret[last])
+			i++
+		}
+
+		if this.dialect == Calculator && runes[startPosition] == '-' && isFactorialAt(runes, i) {
+			// the factorial binds tighter than the minus (i.e. '-3!' is '-(3!)')
+			ret = append(ret[:last], splitNegativeNumber(ret[last])...)
+		}
+
+		if this.units {
+			if unitToken, found := readUnitAfterNumber(runes, i); found {
+				ret = append(ret, unitToken)
+				i = unitToken.StartPosition + unitToken.Length
+				isFormulaSubPart = false
+			}
+		}
+		return i
+	}
+
+	for i := this.formulaStart(runes); i < runesLength; i++ {
+		if this.maxTokens > 0 && len(ret) > this.maxTokens {
+			return nil, append(errs, &MaxTokensError{Position: ret[this.maxTokens].StartPosition, Limit: this.maxTokens})
+		}
+
+		if end, found := radixLiteralEnd(runes, i, isFormulaSubPart); found {
+			literal := string(runes[i:end])
+			intVal, err := strconv.ParseInt(literal, 0, 64)
+			if err == nil && (intVal > maxExactInteger || intVal < -maxExactInteger) {
+				err = strconv.ErrRange
+			}
+
+			if err == nil {
+				ret = append(ret, token{Type: tt_INTEGER,
+					Value:         intVal,
+					StartPosition: i,
+					Length:        end - i})
+				isFormulaSubPart = false
+				end = readAfterNumber(end, i)
+			} else if errors.Is(err, strconv.ErrRange) {
+				addInvalidToken(&SyntaxError{Code: ErrorCodeInvalidNumber,
+					Message:  fmt.Sprintf("the integer literal '%s' is out of range, the limit is ±2^53", literal),
+					Token:    literal,
+					Position: i,
+					Length:   end - i})
+			} else {
+				addInvalidToken(&SyntaxError{Code: ErrorCodeInvalidNumber,
+					Message:  fmt.Sprintf("invalid integer literal '%s'", literal),
+					Token:    literal,
+					Position: i,
+					Length:   end - i})
+			}
+			i = end - 1
+			continue
+		}
+
 		if this.isPartOfNumeric(runes[i], true, false, isFormulaSubPart) {
 			buffer := make([]rune, 0)
 			buffer = append(buffer, runes[i])
 			startPosition := i
+			isInvalid := false
 
 			i++
 			for i < runesLength {
+				if isDigitSeparator(runes, i) {
+					// i.e. '1_000_000', the separator is not part of the number
+					i++
+					continue
+				}
+
 				if !this.isPartOfNumeric(runes[i], false, runes[i-1] == '-', isFormulaSubPart) {
 					break
 				}
 
-				if isScientific && this.isScientificNotation(runes[i]) {
-					return nil, fmt.Errorf("invalid token '%s' detected at position '%d'", string(runes[i]), i)
+				if isScientific && this.isScientificNotation(runes[i]) && !isInvalid {
+					addInvalidToken(newInvalidTokenError(runes, i))
+					isInvalid = true
 				}
 
 				if this.isScientificNotation(runes[i]) {
@@ -58,7 +164,10 @@ func (this tokenReader) read(formula string) ([]token, error) {
 				i++
 			}
 
-			if intVal, err := strconv.ParseInt(string(buffer), 10, 64); err == nil {
+			if isInvalid {
+				// the number was already reported, it is skipped as a whole
+				isScientific = false
+			} else if intVal, err := strconv.ParseInt(string(buffer), 10, 64); err == nil {
 				ret = append(ret, token{Type: tt_INTEGER,
 					Value:         intVal,
 					StartPosition: startPosition,
@@ -79,10 +188,18 @@ func (this tokenReader) read(formula string) ([]token, error) {
 						StartPosition: startPosition,
 						Length:        i - startPosition})
 				} else {
-					return nil, fmt.Errorf("invalid floating point number: %s", string(buffer))
+					addInvalidToken(&SyntaxError{Code: ErrorCodeInvalidNumber,
+						Message:  fmt.Sprintf("invalid floating point number '%s'", string(buffer)),
+						Token:    string(buffer),
+						Position: startPosition,
+						Length:   i - startPosition})
 				}
 			}
 
+			if !isInvalid {
+				i = readAfterNumber(i, startPosition)
+			}
+
 			if i == runesLength {
 				continue
 			}
@@ -103,19 +220,27 @@ func (this tokenReader) read(formula string) ([]token, error) {
 				i++
 			}
 
-			ret = append(ret, token{Type: tt_TEXT,
-				Value:         string(buffer),
-				StartPosition: startPosition,
-				Length:        i - startPosition})
-
-			isFormulaSubPart = false
+			if string(buffer) == "xor" || (!this.caseSensitive && strings.EqualFold(string(buffer), "xor")) {
+				// bitwise exclusive or
+				ret = append(ret, token{Type: tt_OPERATION,
+					Value:         '⊕',
+					StartPosition: startPosition,
+					Length:        i - startPosition})
+				isFormulaSubPart = true
+			} else {
+				ret = append(ret, token{Type: tt_TEXT,
+					Value:         string(buffer),
+					StartPosition: startPosition,
+					Length:        i - startPosition})
+				isFormulaSubPart = false
+			}
 
 			if i == runesLength {
 				continue
 			}
 		}
 
-		if runes[i] == this.argumentSeparator {
+		if runes[i] == this.argumentSeparator || (this.dialect == Excel && runes[i] == ';') {
 			ret = append(ret, token{Type: tt_ARGUMENT_SEPARATOR,
 				Value:         runes[i],
 				StartPosition: i,
@@ -125,7 +250,65 @@ func (this tokenReader) read(formula string) ([]token, error) {
 			switch runes[i] {
 			case ' ':
 				continue
+			case '"':
+				content, length, found := readQuoted(runes, i)
+				if !found {
+					addInvalidToken(&SyntaxError{Code: ErrorCodeInvalidToken,
+						Message:  "missing closing quote of the string",
+						Token:    string(runes[i:]),
+						Position: i,
+						Length:   length})
+				} else {
+					ret = append(ret, token{Type: tt_STRING,
+						Value:         content,
+						StartPosition: i,
+						Length:        length})
+				}
+				i += length - 1
+				isFormulaSubPart = false
+			case '#':
+				content, length, found := readQuoted(runes, i)
+				if !found {
+					// a lone '#' is an unknown character
+					addInvalidToken(newInvalidTokenError(runes, i))
+					continue
+				}
+
+				if date, err := parseDate(content); err != nil {
+					addInvalidToken(&SyntaxError{Code: ErrorCodeInvalidDate,
+						Message:  fmt.Sprintf("invalid date '%s', expected a date like #2006-01-02# or #2006-01-02T15:04:05Z#", string(runes[i:i+length])),
+						Token:    string(runes[i : i+length]),
+						Position: i,
+						Length:   length})
+				} else {
+					ret = append(ret, token{Type: tt_DATE,
+						Value:         date,
+						StartPosition: i,
+						Length:        length})
+				}
+				i += length - 1
+				isFormulaSubPart = false
 			case '+', '-', '*', '/', '^', '%', '≤', '≥', '≠':
+				if runes[i] == '%' && this.dialect == Calculator && isAfterOperand(ret) && !this.isFollowedByOperand(runes, i+1) {
+					ret = append(ret, token{Type: tt_OPERATION,
+						Value:         '％',
+						StartPosition: i,
+						Length:        1})
+					isFormulaSubPart = false
+					continue
+				}
+
+				if runes[i] == '/' && i+1 < runesLength && runes[i+1] == '/' {
+					// integer division
+					ret = append(ret, token{Type: tt_OPERATION,
+						Value:         '⫽',
+						StartPosition: i,
+						Length:        2})
+					i++
+					isFormulaSubPart = true
+					continue
+				}
+
 				if this.isUnaryMinus(runes[i], ret) {
 					ret = append(ret, token{Type: tt_OPERATION,
 						Value:         '_',
@@ -139,6 +322,41 @@ func (this tokenReader) read(formula string) ([]token, error) {
 
 				}
 				isFormulaSubPart = true
+			case '[':
+				if !this.units || len(ret) == 0 || !(ret[len(ret)-1].Type == tt_INTEGER || ret[len(ret)-1].Type == tt_FLOATING_POINT || ret[len(ret)-1].Type == tt_TEXT) {
+					addInvalidToken(newInvalidTokenError(runes, i))
+					continue
+				}
+
+				length := 1
+				for i+length < runesLength && runes[i+length] != ']' {
+					length++
+				}
+				if i+length == runesLength {
+					addInvalidToken(&SyntaxError{Code: ErrorCodeInvalidToken,
+						Message:  "missing closing bracket of the unit",
+						Token:    string(runes[i:]),
+						Position: i,
+						Length:   length})
+					i += length - 1
+					continue
+				}
+				length++
+
+				if unit, err := parseUnit(string(runes[i+1 : i+length-1])); err != nil {
+					addInvalidToken(&SyntaxError{Code: ErrorCodeUnknownUnit,
+						Message:  err.Error(),
+						Token:    string(runes[i : i+length]),
+						Position: i,
+						Length:   length})
+				} else {
+					ret = append(ret, token{Type: tt_UNIT,
+						Value:         unit,
+						StartPosition: i,
+						Length:        length})
+				}
+				i += length - 1
+				isFormulaSubPart = false
 			case '(':
 
 				ret = append(ret, token{Type: tt_LEFT_BRACKET,
@@ -157,7 +375,19 @@ func (this tokenReader) read(formula string) ([]token, error) {
 					ret = append(ret, token{Type: tt_OPERATION,
 						Value:         '≤',
 						StartPosition: i,
-						Length:        1})
+						Length:        2})
+					i++
+				} else if this.dialect == Excel && i+1 < runesLength && runes[i+1] == '>' {
+					ret = append(ret, token{Type: tt_OPERATION,
+						Value:         '≠',
+						StartPosition: i,
+						Length:        2})
+					i++
+				} else if i+1 < runesLength && runes[i+1] == '<' {
+					ret = append(ret, token{Type: tt_OPERATION,
+						Value:         '≪',
+						StartPosition: i,
+						Length:        2})
 					i++
 				} else {
 					ret = append(ret, token{Type: tt_OPERATION,
@@ -171,7 +401,13 @@ func (this tokenReader) read(formula string) ([]token, error) {
 					ret = append(ret, token{Type: tt_OPERATION,
 						Value:         '≥',
 						StartPosition: i,
-						Length:        1})
+						Length:        2})
+					i++
+				} else if i+1 < runesLength && runes[i+1] == '>' {
+					ret = append(ret, token{Type: tt_OPERATION,
+						Value:         '≫',
+						StartPosition: i,
+						Length:        2})
 					i++
 				} else {
 					ret = append(ret, token{Type: tt_OPERATION,
@@ -189,31 +425,62 @@ func (this tokenReader) read(formula string) ([]token, error) {
 					i++
 
 					isFormulaSubPart = false
+				} else if this.dialect == Calculator && isAfterOperand(ret) {
+					// factorial
+					ret = append(ret, token{Type: tt_OPERATION,
+						Value:         '!',
+						StartPosition: i,
+						Length:        1})
+					isFormulaSubPart = false
 				} else {
-					return nil, fmt.Errorf("invalid token '%s' detected at position '%d'", string(runes[i]), i)
+					addInvalidToken(newInvalidTokenError(runes, i))
 				}
 			case '&':
 				if i+1 < runesLength && runes[i+1] == '&' {
 					ret = append(ret, token{Type: tt_OPERATION,
-						Value:         '&',
+						Value:         '∧',
 						StartPosition: i,
 						Length:        2})
 					i++
 					isFormulaSubPart = false
+				} else if this.dialect == Excel {
+					// concatenation
+					ret = append(ret, token{Type: tt_OPERATION,
+						Value:         '⧺',
+						StartPosition: i,
+						Length:        1})
+					isFormulaSubPart = true
 				} else {
-					return nil, fmt.Errorf("invalid token '%s' detected at position '%d'", string(runes[i]), i)
+					// bitwise and
+					ret = append(ret, token{Type: tt_OPERATION,
+						Value:         '&',
+						StartPosition: i,
+						Length:        1})
+					isFormulaSubPart = true
 				}
 			case '|':
 				if i+1 < runesLength && runes[i+1] == '|' {
 					ret = append(ret, token{Type: tt_OPERATION,
-						Value:         '|',
+						Value:         '∨',
 						StartPosition: i,
 						Length:        2})
 					i++
 					isFormulaSubPart = false
 				} else {
-					return nil, fmt.Errorf("invalid token '%s' detected at position '%d'", string(runes[i]), i)
+					// bitwise or
+					ret = append(ret, token{Type: tt_OPERATION,
+						Value:         '|',
+						StartPosition: i,
+						Length:        1})
+					isFormulaSubPart = true
 				}
+			case '~':
+				// bitwise not
+				ret = append(ret, token{Type: tt_OPERATION,
+					Value:         '~',
+					StartPosition: i,
+					Length:        1})
+				isFormulaSubPart = true
 			case '=':
 				if i+1 < runesLength && runes[i+1] == '=' {
 					ret = append(ret, token{Type: tt_OPERATION,
@@ -222,31 +489,193 @@ func (this tokenReader) read(formula string) ([]token, error) {
 						Length:        2})
 					i++
 					isFormulaSubPart = false
+				} else if this.dialect == Excel {
+					ret = append(ret, token{Type: tt_OPERATION,
+						Value:         '=',
+						StartPosition: i,
+						Length:        1})
+					isFormulaSubPart = false
 				} else {
-					return nil, fmt.Errorf("invalid token '%s' detected at position '%d'", string(runes[i]), i)
+					addInvalidToken(newInvalidTokenError(runes, i))
 				}
 			default:
-				return nil, fmt.Errorf("invalid token '%s' detected at position '%d'", string(runes[i]), i)
+				addInvalidToken(newInvalidTokenError(runes, i))
 			}
 		}
 	}
-	return ret, nil
+
+	if this.maxTokens > 0 && len(ret) > this.maxTokens {
+		return nil, append(errs, &MaxTokensError{Position: ret[this.maxTokens].StartPosition, Limit: this.maxTokens})
+	}
+
+	return ret, errs
+}
+
+// Tells whether the next character, after the spaces, is a factorial (a '!' that does not start '!=').
+func isFactorialAt(runes []rune, start int) bool {
+	for i := start; i < len(runes); i++ {
+		if runes[i] != ' ' {
+			return runes[i] == '!' && (i+1 == len(runes) || runes[i+1] != '=')
+		}
+	}
+	return false
+}
+
+// Splits a negative number into a unary minus and the positive number.
+func splitNegativeNumber(number token) []token {
+	minus := token{Type: tt_OPERATION, Value: '_', StartPosition: number.StartPosition, Length: 1}
+
+	number.StartPosition++
+	number.Length--
+	if intVal, ok := number.Value.(int64); ok {
+		number.Value = -intVal
+	} else {
+		number.Value = -number.Value.(float64)
+	}
+	return []token{minus, number}
+}
+
+// Reads the unit written without brackets after a number (i.e. '5 km'), skipping the spaces before it.
+func readUnitAfterNumber(runes []rune, start int) (token, bool) {
+	position := start
+	for position < len(runes) && runes[position] == ' ' {
+		position++
+	}
+
+	if unit, end := readBareUnit(runes, position); unit != nil {
+		return token{Type: tt_UNIT, Value: unit, StartPosition: position, Length: end - position}, true
+	}
+	return token{}, false
+}
+
+// Converts the number of degrees of a literal like '30°' to the angle unit, including the '°' in the token.
+func (this tokenReader) degreeToken(number token) token {
+	number.Length++
+	if this.angleUnit == Degrees {
+		return number
+	}
+
+	degrees, _ := toFloat64(number.Value)
+	number.Type = tt_FLOATING_POINT
+	number.Value = this.angleUnit.fromDegrees(degrees)
+	return number
+}
+
+// Returns where the formula starts: the leading '=' of the Excel formulas is skipped.
+func (this tokenReader) formulaStart(runes []rune) int {
+	if this.dialect != Excel {
+		return 0
+	}
+
+	for i, r := range runes {
+		if r == '=' {
+			return i + 1
+		}
+		if r != ' ' {
+			break
+		}
+	}
+	return 0
+}
+
+/*
+	Read the text delimited by the quote at [start] (i.e. "text" or #2006-01-02#). Within strings, a
+	doubled quote stands for a quote. Returns the text, the number of runes read, quotes included,
+	and whether the closing quote was found.
+*/
+func readQuoted(runes []rune, start int) (string, int, bool) {
+	quote := runes[start]
+	buffer := make([]rune, 0)
+
+	for i := start + 1; i < len(runes); i++ {
+		if runes[i] != quote {
+			buffer = append(buffer, runes[i])
+			continue
+		}
+
+		if quote == '"' && i+1 < len(runes) && runes[i+1] == '"' {
+			buffer = append(buffer, '"')
+			i++
+			continue
+		}
+
+		return string(buffer), i - start + 1, true
+	}
+
+	return string(buffer), len(runes) - start, false
+}
+
+var dateLayouts = []string{"2006-01-02", time.RFC3339Nano, "2006-01-02T15:04:05", "2006-01-02 15:04:05"}
+
+// Parses a date literal, dates without time zone are in UTC.
+func parseDate(text string) (time.Time, error) {
+	var err error
+	for _, layout := range dateLayouts {
+		var date time.Time
+		if date, err = time.Parse(layout, text); err == nil {
+			return date, nil
+		}
+	}
+	return time.Time{}, err
+}
+
+func newInvalidTokenError(runes []rune, position int) *SyntaxError {
+	return &SyntaxError{Code: ErrorCodeInvalidToken,
+		Message:  fmt.Sprintf("invalid token '%s' detected", string(runes[position])),
+		Token:    string(runes[position]),
+		Position: position,
+		Length:   1}
 }
 
 func (this tokenReader) isUnaryMinus(currentToken rune, tokens []token) bool {
 
 	if currentToken == '-' {
+		if len(tokens) == 0 {
+			return true
+		}
 		previousToken := tokens[len(tokens)-1]
 
 		return !(previousToken.Type == tt_FLOATING_POINT ||
 			previousToken.Type == tt_INTEGER ||
 			previousToken.Type == tt_TEXT ||
-			previousToken.Type == tt_RIGHT_BRACKET)
+			previousToken.Type == tt_STRING ||
+			previousToken.Type == tt_DATE ||
+			previousToken.Type == tt_RIGHT_BRACKET ||
+			isPostfixOperation(previousToken))
 	} else {
 		return false
 	}
 }
 
+// Tells whether the last token read ends an operand, so it can be followed by a postfix operator.
+func isAfterOperand(tokens []token) bool {
+	if len(tokens) == 0 {
+		return false
+	}
+
+	switch previousToken := tokens[len(tokens)-1]; previousToken.Type {
+	case tt_INTEGER, tt_FLOATING_POINT, tt_TEXT, tt_RIGHT_BRACKET, tt_UNIT:
+		return true
+	default:
+		return isPostfixOperation(previousToken)
+	}
+}
+
+// Tells whether the next character, after the spaces, starts an operand (i.e. the divisor of '50 % 7').
+func (this tokenReader) isFollowedByOperand(runes []rune, start int) bool {
+	for i := start; i < len(runes); i++ {
+		switch {
+		case runes[i] == ' ':
+			continue
+		case runes[i] == '(' || runes[i] == '"' || runes[i] == '#':
+			return true
+		default:
+			return this.isPartOfNumeric(runes[i], true, false, false) || this.isPartOfVariable(runes[i], true)
+		}
+	}
+	return false
+}
+
 func (this tokenReader) isPartOfNumeric(character rune, isFirstCharacter bool, afterMinus bool, isFormulaSubPart bool) bool {
 	return character == this.decimalSeparator || (character >= '0' && character <= '9') || (isFormulaSubPart && isFirstCharacter && character == '-') || (!isFirstCharacter && !afterMinus && character == 'e') || (!isFirstCharacter && character == 'E')
 }
@@ -255,6 +684,45 @@ func (this tokenReader) isPartOfVariable(character rune, isFirstCharacter bool)
 	return (character == '$') || (character >= 'a' && character <= 'z') || (character >= 'A' && character <= 'Z') || (!isFirstCharacter && character >= '0' && character <= '9') || (!isFirstCharacter && character == '_')
 }
 
+/*
+	Returns where the integer literal starting at [start] ends when it has a base prefix: '0x' for
+	hexadecimal, '0b' for binary or '0o' for octal (i.e. '0xFF', '-0b1010' or '0o17'). The letters,
+	digits and '_' that follow the prefix are part of the literal, so '0xFG' is an invalid literal.
+*/
+func radixLiteralEnd(runes []rune, start int, isFormulaSubPart bool) (int, bool) {
+	i := start
+	if isFormulaSubPart && i < len(runes) && runes[i] == '-' {
+		i++
+	}
+
+	if i+1 >= len(runes) || runes[i] != '0' {
+		return 0, false
+	}
+	switch runes[i+1] {
+	case 'x', 'X', 'b', 'B', 'o', 'O':
+	default:
+		return 0, false
+	}
+
+	end := i + 2
+	for end < len(runes) && (runes[end] == '_' || unicode.IsLetter(runes[end]) || unicode.IsDigit(runes[end])) {
+		end++
+	}
+	return end, true
+}
+
+/*
+	Tells whether the '_' at [position] separates two digits of a number (i.e. '1_000_000'). Unlike
+	',' and ';', it is never the decimal or the argument separator.
+*/
+func isDigitSeparator(runes []rune, position int) bool {
+	return runes[position] == '_' && position > 0 && position+1 < len(runes) && isDecimalDigit(runes[position-1]) && isDecimalDigit(runes[position+1])
+}
+
+func isDecimalDigit(character rune) bool {
+	return character >= '0' && character <= '9'
+}
+
 func (this tokenReader) isScientificNotation(char rune) bool {
 	return char == 'e' || char == 'E'
 }
@@ -1,8 +1,10 @@
 package gojacego
 
 import (
+	"math"
 	"strings"
 	"testing"
+	"time"
 )
 
 func errorContains(out error, want string) bool {
@@ -525,3 +527,276 @@ func TestTokenReader38(test *testing.T) {
 		test.Errorf("error should not be null")
 	}
 }
+
+func TestTokenReaderReadAll(test *testing.T) {
+	reader := newTokenReader('.', ',')
+	ret, errs := reader.readAll("1 # 2 @ 0..3")
+
+	if len(errs) != 3 {
+		test.Fatalf("errors - expected: 3, got: %d", len(errs))
+	}
+
+	if !errorContains(errs[0], "'#'") || !errorContains(errs[1], "'@'") || !errorContains(errs[2], "'0..3'") {
+		test.Errorf("unexpected errors: %v", errs)
+	}
+
+	testLen(test, ret, 5)
+	testToken(test, ret[1], "#", 2, 1)
+	testToken(test, ret[2], "2", 4, 1)
+	testToken(test, ret[4], "0..3", 8, 4)
+
+	if ret[1].Type != tt_INVALID || ret[3].Type != tt_INVALID || ret[4].Type != tt_INVALID {
+		test.Errorf("expected: invalid tokens")
+	}
+}
+
+func TestTokenReaderStrings(test *testing.T) {
+	reader := newTokenReader('.', ',')
+	ret, err := reader.read(`datediff("day", a, "say ""hi""")`)
+
+	if err != nil {
+		test.Fatalf("unexpected error: %v", err)
+	}
+
+	testLen(test, ret, 8)
+	testToken(test, ret[2], "day", 9, 5)
+	testToken(test, ret[6], `say "hi"`, 19, 12)
+
+	if ret[2].Type != tt_STRING || ret[6].Type != tt_STRING {
+		test.Errorf("expected: string tokens")
+	}
+
+	if _, err := reader.read(`"abc + 1`); !errorContains(err, "missing closing quote") {
+		test.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestTokenReaderDates(test *testing.T) {
+	reader := newTokenReader('.', ',')
+	ret, err := reader.read("#2024-01-31# - -#2024-01-31T10:30:00+02:00#")
+
+	if err != nil {
+		test.Fatalf("unexpected error: %v", err)
+	}
+
+	testLen(test, ret, 4)
+
+	if ret[0].Type != tt_DATE || ret[3].Type != tt_DATE || ret[2].Value != '_' {
+		test.Errorf("unexpected tokens: %v", ret)
+	}
+
+	if date := ret[0].Value.(time.Time); !date.Equal(time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC)) {
+		test.Errorf("expected: 2024-01-31, got: %v", date)
+	}
+
+	if date := ret[3].Value.(time.Time); !date.Equal(time.Date(2024, 1, 31, 8, 30, 0, 0, time.UTC)) {
+		test.Errorf("expected: 2024-01-31T08:30:00Z, got: %v", date)
+	}
+
+	if _, err := reader.read("#2024-13-01# + 1"); !errorContains(err, "invalid date '#2024-13-01#'") {
+		test.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestTokenReaderDegrees(test *testing.T) {
+	reader := newTokenReader('.', ',')
+	ret, err := reader.read("-30° + 45.5°")
+
+	if err != nil {
+		test.Fatalf("unexpected error: %v", err)
+	}
+
+	testLen(test, ret, 3)
+
+	if ret[0].Type != tt_FLOATING_POINT || math.Abs(ret[0].Value.(float64)+math.Pi/6) > 1e-15 || ret[0].StartPosition != 0 || ret[0].Length != 4 {
+		test.Errorf("expected: -π/6 at 0 of length 4, got: %v", ret[0])
+	}
+
+	if ret[2].Type != tt_FLOATING_POINT || ret[2].StartPosition != 7 || ret[2].Length != 5 {
+		test.Errorf("expected: a number at 7 of length 5, got: %v", ret[2])
+	}
+
+	reader.angleUnit = Degrees
+	ret, _ = reader.read("30°")
+	if ret[0].Type != tt_INTEGER || ret[0].Value != int64(30) {
+		test.Errorf("expected: 30, got: %v", ret[0])
+	}
+
+	reader.angleUnit = Gradians
+	ret, _ = reader.read("90°")
+	if ret[0].Value != 100.0 {
+		test.Errorf("expected: 100, got: %v", ret[0])
+	}
+
+	if _, err := reader.read("x°"); err == nil {
+		test.Errorf("error should not be null")
+	}
+}
+
+func TestTokenReaderUnits(test *testing.T) {
+	reader := newTokenReader('.', ',')
+	reader.units = true
+	ret, err := reader.read("5 km/h * x[m/s]")
+
+	if err != nil {
+		test.Fatalf("unexpected error: %v", err)
+	}
+
+	testLen(test, ret, 5)
+
+	if ret[1].Type != tt_UNIT || ret[1].Value.(*unit).String() != "km/h" || ret[1].StartPosition != 2 || ret[1].Length != 4 {
+		test.Errorf("expected: unit 'km/h' at 2 of length 4, got: %v", ret[1])
+	}
+
+	if ret[2].Type != tt_OPERATION || ret[2].Value != '*' {
+		test.Errorf("expected: '*', got: %v", ret[2])
+	}
+
+	if ret[4].Type != tt_UNIT || ret[4].Value.(*unit).String() != "m/s" || ret[4].StartPosition != 10 || ret[4].Length != 5 {
+		test.Errorf("expected: unit 'm/s' at 10 of length 5, got: %v", ret[4])
+	}
+
+	if _, err := reader.read("5[foo]"); err == nil {
+		test.Errorf("error should not be null")
+	}
+
+	if _, err := reader.read("5[m"); err == nil {
+		test.Errorf("error should not be null")
+	}
+
+	reader.units = false
+	if _, err := reader.read("x[m]"); err == nil {
+		test.Errorf("error should not be null")
+	}
+}
+
+func TestTokenReaderCalculatorDialect(test *testing.T) {
+	reader := newTokenReader('.', ',')
+	reader.dialect = Calculator
+	ret, err := reader.read("-3! - 15% + 7 % 2")
+
+	if err != nil {
+		test.Fatalf("unexpected error: %v", err)
+	}
+
+	testLen(test, ret, 10)
+
+	expected := []struct {
+		tokenType tokenType
+		value     interface{}
+	}{
+		{tt_OPERATION, '_'}, {tt_INTEGER, int64(3)}, {tt_OPERATION, '!'}, {tt_OPERATION, '-'}, {tt_INTEGER, int64(15)},
+		{tt_OPERATION, '％'}, {tt_OPERATION, '+'}, {tt_INTEGER, int64(7)}, {tt_OPERATION, '%'}, {tt_INTEGER, int64(2)},
+	}
+
+	for i, item := range expected {
+		if ret[i].Type != item.tokenType || ret[i].Value != item.value {
+			test.Errorf("token %d, expected: %v, got: %v", i, item.value, ret[i])
+		}
+	}
+
+	if ret[1].StartPosition != 1 || ret[1].Length != 1 {
+		test.Errorf("expected: 3 at 1 of length 1, got: %v", ret[1])
+	}
+
+	reader.dialect = DefaultDialect
+	if _, err := reader.read("3!"); err == nil {
+		test.Errorf("error should not be null")
+	}
+}
+
+func TestTokenReaderBitwiseOperators(test *testing.T) {
+	reader := newTokenReader('.', ',')
+	ret, err := reader.read("~a & b | c xor d << 1 >> 2 // 3 && e || f")
+
+	if err != nil {
+		test.Fatalf("unexpected error: %v", err)
+	}
+
+	testLen(test, ret, 18)
+
+	expected := []struct {
+		index    int
+		value    rune
+		position int
+		length   int
+	}{
+		{0, '~', 0, 1}, {2, '&', 3, 1}, {4, '|', 7, 1}, {6, '⊕', 11, 3}, {8, '≪', 17, 2},
+		{10, '≫', 22, 2}, {12, '⫽', 27, 2}, {14, '∧', 32, 2}, {16, '∨', 37, 2},
+	}
+
+	for _, item := range expected {
+		t := ret[item.index]
+		if t.Type != tt_OPERATION || t.Value != item.value || t.StartPosition != item.position || t.Length != item.length {
+			test.Errorf("expected: %c at %d of length %d, got: %v", item.value, item.position, item.length, t)
+		}
+	}
+
+	// like the names, 'xor' is case insensitive unless the reader is case sensitive
+	ret, _ = reader.read("a XOR b")
+	if ret[1].Type != tt_OPERATION || ret[1].Value != '⊕' {
+		test.Errorf("expected: exclusive or, got: %v", ret[1])
+	}
+
+	reader.caseSensitive = true
+	ret, _ = reader.read("a XOR b")
+	if ret[1].Type != tt_TEXT || ret[1].Value != "XOR" {
+		test.Errorf("expected: the name XOR, got: %v", ret[1])
+	}
+	reader.caseSensitive = false
+
+	reader.dialect = Excel
+	ret, _ = reader.read(`"a" & 1`)
+	if ret[1].Value != '⧺' {
+		test.Errorf("expected: concatenation, got: %v", ret[1])
+	}
+}
+
+func TestTokenReaderIntegerLiterals(test *testing.T) {
+	reader := newTokenReader('.', ',')
+
+	scenarios := []struct {
+		formula  string
+		expected int64
+	}{
+		{formula: "0xFF", expected: 255},
+		{formula: "0Xff", expected: 255},
+		{formula: "0b1010", expected: 10},
+		{formula: "0o17", expected: 15},
+		{formula: "-0x10", expected: -16},
+		{formula: "0xFF_FF", expected: 65535},
+		{formula: "1_000_000", expected: 1000000},
+		{formula: "0x20000000000000", expected: 1 << 53},
+		{formula: "-0x20000000000000", expected: -1 << 53},
+	}
+
+	for _, scenario := range scenarios {
+		ret, err := reader.read(scenario.formula)
+		if err != nil {
+			test.Errorf("%s => unexpected error: %v", scenario.formula, err)
+			continue
+		}
+
+		testLen(test, ret, 1)
+		if ret[0].Type != tt_INTEGER || ret[0].Value != scenario.expected || ret[0].Length != len(scenario.formula) {
+			test.Errorf("%s => expected: %d, got: %v", scenario.formula, scenario.expected, ret[0])
+		}
+	}
+
+	for _, formula := range []string{"0x", "0xFG", "0b102", "0xFFFFFFFFFFFFFFFF", "1__0", "1_"} {
+		if _, err := reader.read(formula); err == nil {
+			test.Errorf("%s => error should not be null", formula)
+		}
+	}
+
+	for _, formula := range []string{"0x20000000000001", "-0b100000000000000000000000000000000000000000000000000001", "0x7FFFFFFFFFFFFFFF", "0xFFFFFFFFFFFFFFFFFF"} {
+		if _, err := reader.read(formula); err == nil || !strings.Contains(err.Error(), "out of range") {
+			test.Errorf("%s => expected: an out of range error, got: %v", formula, err)
+		}
+	}
+
+	ret, _ := reader.read("1_000.5")
+	if ret[0].Type != tt_FLOATING_POINT || ret[0].Value != 1000.5 || ret[0].Length != 7 {
+		test.Errorf("expected: 1000.5 of length 7, got: %v", ret[0])
+	}
+}
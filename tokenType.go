@@ -10,4 +10,10 @@ const (
 	tt_LEFT_BRACKET
 	tt_RIGHT_BRACKET
 	tt_ARGUMENT_SEPARATOR
+	tt_STRING
+	tt_DATE
+	// the unit of the number or the variable before it
+	tt_UNIT
+	// part of a formula that cannot be read, only kept while recovering from errors
+	tt_INVALID
 )
@@ -0,0 +1,807 @@
+package gojacego
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+/*
+	The exponents of the SI base quantities of a unit: length, mass, time, electric current,
+	temperature, amount of substance and luminous intensity.
+*/
+type dimension [7]int
+
+var (
+	dimensionless        = dimension{}
+	dimensionLength      = dimension{1, 0, 0, 0, 0, 0, 0}
+	dimensionMass        = dimension{0, 1, 0, 0, 0, 0, 0}
+	dimensionDuration    = dimension{0, 0, 1, 0, 0, 0, 0}
+	dimensionCurrent     = dimension{0, 0, 0, 1, 0, 0, 0}
+	dimensionTemperature = dimension{0, 0, 0, 0, 1, 0, 0}
+	dimensionAmount      = dimension{0, 0, 0, 0, 0, 1, 0}
+	dimensionLuminosity  = dimension{0, 0, 0, 0, 0, 0, 1}
+)
+
+// Returns the dimension of the product of the units, or of their quotient when [sign] is -1.
+func (this dimension) combine(other dimension, sign int) dimension {
+	for idx := range this {
+		this[idx] += sign * other[idx]
+	}
+	return this
+}
+
+func (this dimension) power(exponent int) dimension {
+	for idx := range this {
+		this[idx] *= exponent
+	}
+	return this
+}
+
+// A unit of the table: its value in SI base units and whether it takes the SI prefixes (i.e. 'km').
+type unitDefinition struct {
+	scale      float64
+	dimension  dimension
+	prefixable bool
+}
+
+var (
+	dimensionForce    = dimension{1, 1, -2, 0, 0, 0, 0}
+	dimensionEnergy   = dimension{2, 1, -2, 0, 0, 0, 0}
+	dimensionPower    = dimension{2, 1, -3, 0, 0, 0, 0}
+	dimensionPressure = dimension{-1, 1, -2, 0, 0, 0, 0}
+	dimensionSpeed    = dimension{1, 0, -1, 0, 0, 0, 0}
+	dimensionArea     = dimension{2, 0, 0, 0, 0, 0, 0}
+	dimensionVolume   = dimension{3, 0, 0, 0, 0, 0, 0}
+)
+
+// The units that can be used in the formulas, by symbol.
+var unitTable = map[string]unitDefinition{
+	// SI base units, the kilogram is the prefixed gram
+	"m":   {scale: 1, dimension: dimensionLength, prefixable: true},
+	"g":   {scale: 1e-3, dimension: dimensionMass, prefixable: true},
+	"s":   {scale: 1, dimension: dimensionDuration, prefixable: true},
+	"A":   {scale: 1, dimension: dimensionCurrent, prefixable: true},
+	"K":   {scale: 1, dimension: dimensionTemperature, prefixable: true},
+	"mol": {scale: 1, dimension: dimensionAmount, prefixable: true},
+	"cd":  {scale: 1, dimension: dimensionLuminosity, prefixable: true},
+
+	// SI derived units
+	"N":   {scale: 1, dimension: dimensionForce, prefixable: true},
+	"J":   {scale: 1, dimension: dimensionEnergy, prefixable: true},
+	"W":   {scale: 1, dimension: dimensionPower, prefixable: true},
+	"Pa":  {scale: 1, dimension: dimensionPressure, prefixable: true},
+	"Hz":  {scale: 1, dimension: dimension{0, 0, -1, 0, 0, 0, 0}, prefixable: true},
+	"C":   {scale: 1, dimension: dimension{0, 0, 1, 1, 0, 0, 0}, prefixable: true},
+	"V":   {scale: 1, dimension: dimension{2, 1, -3, -1, 0, 0, 0}, prefixable: true},
+	"ohm": {scale: 1, dimension: dimension{2, 1, -3, -2, 0, 0, 0}, prefixable: true},
+	"Ω":   {scale: 1, dimension: dimension{2, 1, -3, -2, 0, 0, 0}, prefixable: true},
+
+	// units accepted for use with the SI
+	"min": {scale: 60, dimension: dimensionDuration},
+	"h":   {scale: 3600, dimension: dimensionDuration},
+	"d":   {scale: 86400, dimension: dimensionDuration},
+	"L":   {scale: 1e-3, dimension: dimensionVolume, prefixable: true},
+	"l":   {scale: 1e-3, dimension: dimensionVolume, prefixable: true},
+	"t":   {scale: 1000, dimension: dimensionMass},
+	"ha":  {scale: 1e4, dimension: dimensionArea},
+	"bar": {scale: 1e5, dimension: dimensionPressure, prefixable: true},
+	"Wh":  {scale: 3600, dimension: dimensionEnergy, prefixable: true},
+	"eV":  {scale: 1.602176634e-19, dimension: dimensionEnergy, prefixable: true},
+
+	// imperial and US customary units
+	"in":   {scale: 0.0254, dimension: dimensionLength},
+	"ft":   {scale: 0.3048, dimension: dimensionLength},
+	"yd":   {scale: 0.9144, dimension: dimensionLength},
+	"mi":   {scale: 1609.344, dimension: dimensionLength},
+	"nmi":  {scale: 1852, dimension: dimensionLength},
+	"acre": {scale: 4046.8564224, dimension: dimensionArea},
+	"gal":  {scale: 3.785411784e-3, dimension: dimensionVolume},
+	"oz":   {scale: 0.028349523125, dimension: dimensionMass},
+	"lb":   {scale: 0.45359237, dimension: dimensionMass},
+	"lbf":  {scale: 4.4482216152605, dimension: dimensionForce},
+	"psi":  {scale: 6894.757293168361, dimension: dimensionPressure},
+	"mph":  {scale: 0.44704, dimension: dimensionSpeed},
+	"kn":   {scale: 1852.0 / 3600, dimension: dimensionSpeed},
+	"cal":  {scale: 4.184, dimension: dimensionEnergy, prefixable: true},
+	"BTU":  {scale: 1055.05585262, dimension: dimensionEnergy},
+	"hp":   {scale: 745.69987158227022, dimension: dimensionPower},
+	"atm":  {scale: 101325, dimension: dimensionPressure},
+}
+
+// The SI prefixes, the longest first so 'da' is tried before 'd'.
+var unitPrefixes = []struct {
+	symbol string
+	factor float64
+}{
+	{"da", 1e1}, {"Y", 1e24}, {"Z", 1e21}, {"E", 1e18}, {"P", 1e15}, {"T", 1e12}, {"G", 1e9}, {"M", 1e6},
+	{"k", 1e3}, {"h", 1e2}, {"d", 1e-1}, {"c", 1e-2}, {"m", 1e-3}, {"µ", 1e-6}, {"u", 1e-6},
+	{"n", 1e-9}, {"p", 1e-12}, {"f", 1e-15}, {"a", 1e-18}, {"z", 1e-21}, {"y", 1e-24},
+}
+
+// Returns the definition of a unit symbol, which can be a prefixed unit of the table (i.e. 'km').
+func lookupUnitSymbol(symbol string) (unitDefinition, bool) {
+	if definition, found := unitTable[symbol]; found {
+		return definition, true
+	}
+
+	for _, prefix := range unitPrefixes {
+		if !strings.HasPrefix(symbol, prefix.symbol) {
+			continue
+		}
+		if definition, found := unitTable[symbol[len(prefix.symbol):]]; found && definition.prefixable {
+			definition.scale *= prefix.factor
+			return definition, true
+		}
+	}
+	return unitDefinition{}, false
+}
+
+// A unit symbol raised to a power, i.e. 's^-2'.
+type unitFactor struct {
+	symbol   string
+	exponent int
+}
+
+/*
+	A unit as written in the formulas, i.e. 'km/h'. Its factors are kept, rather than only its
+	dimension, so the results are given in the units of the formula.
+*/
+type unit struct {
+	factors []unitFactor
+	// the value of the unit in SI base units
+	scale     float64
+	dimension dimension
+}
+
+// Creates the unit made of the [factors], merging the factors of the same symbol.
+func newUnit(factors []unitFactor) *unit {
+	merged := make([]unitFactor, 0, len(factors))
+	for _, factor := range factors {
+		found := false
+		for idx := range merged {
+			if merged[idx].symbol == factor.symbol {
+				merged[idx].exponent += factor.exponent
+				found = true
+				break
+			}
+		}
+		if !found {
+			merged = append(merged, factor)
+		}
+	}
+
+	ret := &unit{factors: make([]unitFactor, 0, len(merged)), scale: 1}
+	for _, factor := range merged {
+		if factor.exponent == 0 {
+			continue
+		}
+		definition, _ := lookupUnitSymbol(factor.symbol)
+		ret.factors = append(ret.factors, factor)
+		ret.scale *= math.Pow(definition.scale, float64(factor.exponent))
+		ret.dimension = ret.dimension.combine(definition.dimension.power(factor.exponent), 1)
+	}
+	return ret
+}
+
+// Returns the product of the units, or their quotient when [sign] is -1. A nil unit is a number.
+func multiplyUnits(left *unit, right *unit, sign int) *unit {
+	factors := make([]unitFactor, 0)
+	if left != nil {
+		factors = append(factors, left.factors...)
+	}
+	if right != nil {
+		for _, factor := range right.factors {
+			factors = append(factors, unitFactor{symbol: factor.symbol, exponent: sign * factor.exponent})
+		}
+	}
+	return newUnit(factors)
+}
+
+// Raises the unit to [exponent], which must give integer exponents (i.e. 'm^2' to the power 0.5).
+func (this *unit) power(exponent float64) (*unit, bool) {
+	factors := make([]unitFactor, len(this.factors))
+	for idx, factor := range this.factors {
+		powered := float64(factor.exponent) * exponent
+		if powered != math.Trunc(powered) || math.Abs(powered) > math.MaxInt16 {
+			return nil, false
+		}
+		factors[idx] = unitFactor{symbol: factor.symbol, exponent: int(powered)}
+	}
+	return newUnit(factors), true
+}
+
+func (this *unit) isDimensionless() bool {
+	return this.dimension == dimensionless
+}
+
+func (this *unit) String() string {
+	var numerator, denominator []string
+	for _, factor := range this.factors {
+		text := factor.symbol
+		if exponent := absInt(factor.exponent); exponent != 1 {
+			text += "^" + strconv.Itoa(exponent)
+		}
+		if factor.exponent > 0 {
+			numerator = append(numerator, text)
+		} else {
+			denominator = append(denominator, text)
+		}
+	}
+
+	text := strings.Join(numerator, "*")
+	if len(numerator) == 0 {
+		text = "1"
+	}
+	switch len(denominator) {
+	case 0:
+		return text
+	case 1:
+		return text + "/" + denominator[0]
+	}
+	return text + "/(" + strings.Join(denominator, "*") + ")"
+}
+
+func absInt(value int) int {
+	if value < 0 {
+		return -value
+	}
+	return value
+}
+
+/*
+	Parses a unit like 'km/h', 'kg*m/s^2' or 'm/(s*A)'. The symbols are case sensitive and the
+	exponents are integers.
+*/
+func parseUnit(text string) (*unit, error) {
+	parser := unitParser{runes: []rune(text)}
+
+	factors, err := parser.expression()
+	if err == nil && parser.position < len(parser.runes) {
+		err = fmt.Errorf("unexpected '%c' in the unit '%s'", parser.runes[parser.position], text)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return newUnit(factors), nil
+}
+
+type unitParser struct {
+	runes    []rune
+	position int
+}
+
+func (this *unitParser) peek() rune {
+	for this.position < len(this.runes) && this.runes[this.position] == ' ' {
+		this.position++
+	}
+	if this.position == len(this.runes) {
+		return 0
+	}
+	return this.runes[this.position]
+}
+
+func (this *unitParser) expression() ([]unitFactor, error) {
+	factors, err := this.term()
+	for err == nil {
+		sign := 1
+		switch this.peek() {
+		case '*', '·':
+		case '/':
+			sign = -1
+		default:
+			return factors, nil
+		}
+		this.position++
+
+		var next []unitFactor
+		if next, err = this.term(); err == nil {
+			for _, factor := range next {
+				factors = append(factors, unitFactor{symbol: factor.symbol, exponent: sign * factor.exponent})
+			}
+		}
+	}
+	return nil, err
+}
+
+func (this *unitParser) term() ([]unitFactor, error) {
+	var factors []unitFactor
+
+	switch character := this.peek(); {
+	case character == '(':
+		this.position++
+		inner, err := this.expression()
+		if err != nil {
+			return nil, err
+		}
+		if this.peek() != ')' {
+			return nil, fmt.Errorf("missing ')' in the unit '%s'", string(this.runes))
+		}
+		this.position++
+		factors = inner
+	case character == '1':
+		// the numerator of '1/s'
+		this.position++
+	case isUnitLetter(character):
+		start := this.position
+		for this.position < len(this.runes) && isUnitLetter(this.runes[this.position]) {
+			this.position++
+		}
+		symbol := string(this.runes[start:this.position])
+		if _, found := lookupUnitSymbol(symbol); !found {
+			return nil, fmt.Errorf("unknown unit '%s'", symbol)
+		}
+		factors = []unitFactor{{symbol: symbol, exponent: 1}}
+	default:
+		return nil, fmt.Errorf("invalid unit '%s'", string(this.runes))
+	}
+
+	if this.peek() == '^' {
+		this.position++
+		exponent, length, ok := readUnitExponent(this.runes, this.position)
+		if !ok {
+			return nil, fmt.Errorf("expected an integer exponent in the unit '%s'", string(this.runes))
+		}
+		this.position += length
+		for idx := range factors {
+			factors[idx].exponent *= exponent
+		}
+	}
+	return factors, nil
+}
+
+func isUnitLetter(character rune) bool {
+	return unicode.IsLetter(character)
+}
+
+// Reads an integer exponent, which can be negative, and returns its length.
+func readUnitExponent(runes []rune, start int) (int, int, bool) {
+	end := start
+	if end < len(runes) && runes[end] == '-' {
+		end++
+	}
+	for end < len(runes) && runes[end] >= '0' && runes[end] <= '9' {
+		end++
+	}
+	exponent, err := strconv.Atoi(string(runes[start:end]))
+	return exponent, end - start, err == nil
+}
+
+/*
+	Reads the unit written without brackets after a number (i.e. '9.81 m/s^2'), from [start] to
+	the returned end. It is made of known symbols, integer exponents, '*' and '/' but no spaces or
+	brackets, and it stops before a symbol that is not a unit (i.e. the variable of '5 m/x').
+	Returns nil when the text at [start] is not a unit.
+*/
+func readBareUnit(runes []rune, start int) (*unit, int) {
+	factors := make([]unitFactor, 0)
+	// the end of the last symbol read, a trailing operator belongs to the formula
+	end := start
+	position := start
+	sign := 1
+
+	for {
+		symbolEnd := position
+		for symbolEnd < len(runes) && isUnitLetter(runes[symbolEnd]) {
+			symbolEnd++
+		}
+		symbol := string(runes[position:symbolEnd])
+		if _, found := lookupUnitSymbol(symbol); symbolEnd == position || !found {
+			break
+		}
+
+		factor := unitFactor{symbol: symbol, exponent: sign}
+		if symbolEnd < len(runes) && runes[symbolEnd] == '^' {
+			if exponent, length, ok := readUnitExponent(runes, symbolEnd+1); ok {
+				factor.exponent *= exponent
+				symbolEnd += 1 + length
+			}
+		}
+		factors = append(factors, factor)
+		end = symbolEnd
+
+		if end == len(runes) || (runes[end] != '*' && runes[end] != '/') {
+			break
+		}
+		sign = 1
+		if runes[end] == '/' {
+			sign = -1
+		}
+		position = end + 1
+	}
+
+	if len(factors) == 0 {
+		return nil, start
+	}
+	return newUnit(factors), end
+}
+
+// Creates a quantity of [unit], or a number when the unit is dimensionless.
+func newQuantity(number float64, unit *unit) Value {
+	if unit == nil {
+		return NumberValue(number)
+	}
+	if unit.isDimensionless() {
+		return NumberValue(number * unit.scale)
+	}
+	return Value{kind: KindQuantity, number: number, data: unit}
+}
+
+// Returns the unit of a number (nil) or of a quantity, and whether the value is one of them.
+func quantityUnit(value Value) (*unit, bool) {
+	switch value.kind {
+	case KindNumber:
+		return nil, true
+	case KindQuantity:
+		return value.data.(*unit), true
+	}
+	return nil, false
+}
+
+// Tells whether the units, nil being a number, measure the same quantity.
+func sameDimension(left *unit, right *unit) bool {
+	if left == nil || right == nil {
+		return left == right
+	}
+	return left.dimension == right.dimension
+}
+
+// Returns the name of a unit for the messages, nil being a number.
+func describeUnit(unit *unit) string {
+	if unit == nil {
+		return "a number"
+	}
+	return "'" + unit.String() + "'"
+}
+
+// Converts the [number] of [from] to the unit [to], which must have the same dimension.
+func convertUnit(number float64, from *unit, to *unit) float64 {
+	if from == to {
+		return number
+	}
+	return number * from.scale / to.scale
+}
+
+func newUnitError(operator string, op operation, left *unit, right *unit) *TypeError {
+	_, position, length := describeOperation(op)
+	return &TypeError{Code: ErrorCodeIncompatibleUnits,
+		Message:  fmt.Sprintf("the operator '%s' cannot be applied to %s and %s", operator, describeUnit(left), describeUnit(right)),
+		Token:    operator,
+		Position: position,
+		Length:   length}
+}
+
+/*
+	Applies an arithmetic operator to quantities and numbers: the units are multiplied, divided and
+	raised to a power along with the numbers, and the quantities added, subtracted or compared must
+	have the same dimension. The result is in the unit of the left operand.
+*/
+func (this *evaluationState) quantityArithmetic(operator string, left Value, right Value, op operation) Value {
+	leftUnit, leftOk := quantityUnit(left)
+	rightUnit, rightOk := quantityUnit(right)
+	if !leftOk || !rightOk {
+		panic(newOperatorTypeError(operator, op, left, right))
+	}
+
+	switch operator {
+	case "*":
+		return this.quantityResult(left.number*right.number, multiplyUnits(leftUnit, rightUnit, 1), op)
+	case "/":
+		return this.quantityResult(left.number/right.number, multiplyUnits(leftUnit, rightUnit, -1), op)
+	case "^":
+		if rightUnit != nil {
+			panic(newUnitError(operator, op, leftUnit, rightUnit))
+		}
+		powered, ok := leftUnit.power(right.number)
+		if !ok {
+			_, position, length := describeOperation(op)
+			panic(&TypeError{Code: ErrorCodeIncompatibleUnits,
+				Message:  fmt.Sprintf("the unit '%s' cannot be raised to the power %v", leftUnit, right.number),
+				Token:    operator,
+				Position: position,
+				Length:   length})
+		}
+		return this.quantityResult(math.Pow(left.number, right.number), powered, op)
+	}
+
+	if !sameDimension(leftUnit, rightUnit) {
+		panic(newUnitError(operator, op, leftUnit, rightUnit))
+	}
+
+	converted := convertUnit(right.number, rightUnit, leftUnit)
+	switch operator {
+	case "+":
+		return this.quantityResult(left.number+converted, leftUnit, op)
+	case "-":
+		return this.quantityResult(left.number-converted, leftUnit, op)
+	case "%":
+		return this.quantityResult(math.Mod(left.number, converted), leftUnit, op)
+	}
+	panic(newOperatorTypeError(operator, op, left, right))
+}
+
+// Applies the non-finite policy to the number of a quantity computed by the operation [op].
+func (this *evaluationState) quantityResult(number float64, unit *unit, op operation) Value {
+	checked := this.checkFinite(number, op)
+	return newQuantity(checked.number, unit)
+}
+
+// Compares two quantities of the same dimension and returns -1, 0 or 1.
+func compareQuantities(operator string, left Value, right Value, op operation) int {
+	leftUnit, rightUnit := left.data.(*unit), right.data.(*unit)
+	if !sameDimension(leftUnit, rightUnit) {
+		panic(newUnitError(operator, op, leftUnit, rightUnit))
+	}
+
+	switch converted := convertUnit(right.number, rightUnit, leftUnit); {
+	case left.number < converted:
+		return -1
+	case left.number > converted:
+		return 1
+	}
+	return 0
+}
+
+/*
+	Gives its unit to the value of an annotated variable: a number is a quantity of this unit and a
+	quantity is converted to it.
+*/
+func withVariableUnit(value Value, op *variableOperation) Value {
+	switch value.kind {
+	case KindNull:
+		return value
+	case KindNumber:
+		return newQuantity(value.number, op.Unit)
+	case KindQuantity:
+		if from := value.data.(*unit); sameDimension(from, op.Unit) {
+			return newQuantity(convertUnit(value.number, from, op.Unit), op.Unit)
+		}
+	}
+
+	panic(&TypeError{Code: ErrorCodeIncompatibleUnits,
+		Message:  fmt.Sprintf("the variable '%s' is %s, expected a quantity of '%s'", op.Name, describeValueUnit(value), op.Unit),
+		Token:    op.Name,
+		Position: op.Position,
+		Length:   op.Length})
+}
+
+// Describes the unit of a value for the messages (i.e. "a quantity of 'm'" or "a date").
+func describeValueUnit(value Value) string {
+	if value.kind == KindQuantity {
+		return "a quantity of " + describeUnit(value.data.(*unit))
+	}
+	return "a " + value.kind.String()
+}
+
+// Gives its unit to the number (i.e. '5 km') or to the variable (i.e. 'speed[m/s]') before it.
+func withUnit(operand operation, unitToken token) (operation, error) {
+	unit := unitToken.Value.(*unit)
+
+	switch op := operand.(type) {
+	case *constantOperation:
+		if op.isNumber() {
+			number, _ := toFloat64(op.Value)
+			if value := newQuantity(number, unit); value.kind == KindQuantity {
+				return newConstantOperation(quantity, value), nil
+			} else {
+				return newConstantOperation(floatingPoint, value.number), nil
+			}
+		}
+	case *variableOperation:
+		op.Unit = unit
+		return op, nil
+	}
+	return nil, newUnexpectedTokenError(unitToken)
+}
+
+// The unit of an operation that is known when the formula is built, nil being a number.
+type staticUnit struct {
+	known bool
+	unit  *unit
+}
+
+var (
+	unknownUnit = staticUnit{}
+	numberUnit  = staticUnit{known: true}
+)
+
+/*
+	Verifies, when the formula is built, that the quantities added, subtracted and compared have the
+	same dimension, so 'm + s' fails before it is evaluated. The units of the variables that are not
+	annotated and of the results of the functions are only known during the evaluation.
+*/
+func checkUnits(op operation) (staticUnit, error) {
+	switch cop := op.(type) {
+	case *constantOperation:
+		if cop.isNumber() {
+			return numberUnit, nil
+		}
+		if cop.Metadata.DataType == quantity {
+			return staticUnit{known: true, unit: cop.Value.(Value).data.(*unit)}, nil
+		}
+	case *variableOperation:
+		if cop.Unit != nil {
+			return staticUnit{known: true, unit: cop.Unit}, nil
+		}
+	case *addOperation:
+		return checkSameUnits("+", cop, cop.OperationOne, cop.OperationTwo, false)
+	case *subtractionOperation:
+		return checkSameUnits("-", cop, cop.OperationOne, cop.OperationTwo, false)
+	case *moduloOperation:
+		return checkSameUnits("%", cop, cop.Dividend, cop.Divisor, false)
+	case *lessThanOperation:
+		return checkSameUnits("<", cop, cop.OperationOne, cop.OperationTwo, true)
+	case *lessOrEqualThanOperation:
+		return checkSameUnits("<=", cop, cop.OperationOne, cop.OperationTwo, true)
+	case *greaterThanOperation:
+		return checkSameUnits(">", cop, cop.OperationOne, cop.OperationTwo, true)
+	case *greaterOrEqualThanOperation:
+		return checkSameUnits(">=", cop, cop.OperationOne, cop.OperationTwo, true)
+	case *equalOperation:
+		return checkSameUnits("==", cop, cop.OperationOne, cop.OperationTwo, true)
+	case *notEqualOperation:
+		return checkSameUnits("!=", cop, cop.OperationOne, cop.OperationTwo, true)
+	case *multiplicationOperation:
+		return checkProductUnits(cop.OperationOne, cop.OperationTwo, 1)
+	case *divisorOperation:
+		return checkProductUnits(cop.Dividend, cop.Divisor, -1)
+	case *exponentiationOperation:
+		return checkPowerUnits(cop)
+	case *unaryMinusOperation:
+		return checkUnits(cop.Operation)
+	case *percentageOperation:
+		return checkUnits(cop.Operation)
+	case *bitwiseNotOperation:
+		return numberUnit, checkOperandUnits(cop.Operation)
+	case *bitwiseAndOperation:
+		return numberUnit, checkOperandUnits(cop.OperationOne, cop.OperationTwo)
+	case *bitwiseOrOperation:
+		return numberUnit, checkOperandUnits(cop.OperationOne, cop.OperationTwo)
+	case *bitwiseXorOperation:
+		return numberUnit, checkOperandUnits(cop.OperationOne, cop.OperationTwo)
+	case *leftShiftOperation:
+		return numberUnit, checkOperandUnits(cop.OperationOne, cop.OperationTwo)
+	case *rightShiftOperation:
+		return numberUnit, checkOperandUnits(cop.OperationOne, cop.OperationTwo)
+	case *integerDivisionOperation:
+		return numberUnit, checkOperandUnits(cop.Dividend, cop.Divisor)
+	case *factorialOperation:
+		operand, err := checkUnits(cop.Operation)
+		if err != nil || !operand.known || operand.unit != nil {
+			return unknownUnit, err
+		}
+		return numberUnit, nil
+	case *andOperation:
+		return numberUnit, checkOperandUnits(cop.OperationOne, cop.OperationTwo)
+	case *orOperation:
+		return numberUnit, checkOperandUnits(cop.OperationOne, cop.OperationTwo)
+	case *concatOperation:
+		return unknownUnit, checkOperandUnits(cop.OperationOne, cop.OperationTwo)
+	case *functionOperation:
+		if err := checkOperandUnits(cop.Arguments...); err != nil {
+			return unknownUnit, err
+		}
+		// the unit of 'convert' is known when it is a constant
+		if cop.Name == "convert" && len(cop.Arguments) == 2 {
+			if target, ok := cop.Arguments[1].(*constantOperation); ok && target.Metadata.DataType == text {
+				if unit, err := parseUnit(target.Value.(string)); err == nil {
+					return staticUnit{known: true, unit: unit}, nil
+				}
+			}
+		}
+	}
+	return unknownUnit, nil
+}
+
+func checkOperandUnits(operands ...operation) error {
+	for _, operand := range operands {
+		if _, err := checkUnits(operand); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func checkSameUnits(operator string, op operation, left operation, right operation, isComparison bool) (staticUnit, error) {
+	leftUnit, err := checkUnits(left)
+	if err != nil {
+		return unknownUnit, err
+	}
+	rightUnit, err := checkUnits(right)
+	if err != nil {
+		return unknownUnit, err
+	}
+
+	if leftUnit.known && rightUnit.known && !sameDimension(leftUnit.unit, rightUnit.unit) {
+		return unknownUnit, newUnitError(operator, op, leftUnit.unit, rightUnit.unit)
+	}
+
+	switch {
+	case isComparison:
+		return numberUnit, nil
+	case leftUnit.known:
+		return leftUnit, nil
+	}
+	return rightUnit, nil
+}
+
+func checkProductUnits(left operation, right operation, sign int) (staticUnit, error) {
+	leftUnit, err := checkUnits(left)
+	if err != nil {
+		return unknownUnit, err
+	}
+	rightUnit, err := checkUnits(right)
+	if err != nil {
+		return unknownUnit, err
+	}
+
+	if !leftUnit.known || !rightUnit.known {
+		return unknownUnit, nil
+	}
+	product := multiplyUnits(leftUnit.unit, rightUnit.unit, sign)
+	if product.isDimensionless() {
+		return numberUnit, nil
+	}
+	return staticUnit{known: true, unit: product}, nil
+}
+
+func checkPowerUnits(op *exponentiationOperation) (staticUnit, error) {
+	base, err := checkUnits(op.Base)
+	if err != nil {
+		return unknownUnit, err
+	}
+	exponent, err := checkUnits(op.Exponent)
+	if err != nil {
+		return unknownUnit, err
+	}
+
+	switch {
+	case base.known && exponent.known && exponent.unit != nil:
+		return unknownUnit, newUnitError("^", op, base.unit, exponent.unit)
+	case base.known && base.unit == nil:
+		return numberUnit, nil
+	case !base.known:
+		return unknownUnit, nil
+	}
+
+	constant, ok := op.Exponent.(*constantOperation)
+	if !ok || !constant.isNumber() {
+		return unknownUnit, nil
+	}
+
+	power, _ := toFloat64(constant.Value)
+	powered, ok := base.unit.power(power)
+	if !ok {
+		return unknownUnit, &TypeError{Code: ErrorCodeIncompatibleUnits,
+			Message:  fmt.Sprintf("the unit '%s' cannot be raised to the power %v", base.unit, power),
+			Token:    "^",
+			Position: op.Position,
+			Length:   op.Length}
+	}
+	return staticUnit{known: true, unit: powered}, nil
+}
+
+/*
+	Registers the functions of the units: 'convert(x, "km/h")' converts the quantity 'x' to the unit
+	"km/h", which must measure the same quantity.
+*/
+func registryUnitFunctions(registry *functionRegistry) {
+	registry.registerValueFunction("convert", withNullArguments(func(arguments []Value) (Value, error) {
+		name, ok := arguments[1].Text()
+		if !ok {
+			return nullValue, fmt.Errorf("the argument 2 is a %s, expected a unit like \"km/h\"", arguments[1].kind)
+		}
+		target, err := parseUnit(name)
+		if err != nil {
+			return nullValue, err
+		}
+
+		from, ok := quantityUnit(arguments[0])
+		if !ok || from == nil || !sameDimension(from, target) {
+			return nullValue, fmt.Errorf("the argument 1 is %s, expected a quantity of '%s'", describeValueUnit(arguments[0]), target)
+		}
+		return newQuantity(convertUnit(arguments[0].number, from, target), target), nil
+	}), 2, 2, true, true)
+}
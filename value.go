@@ -0,0 +1,304 @@
+package gojacego
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"time"
+)
+
+/*
+	ValueKind tells what a Value holds.
+*/
+type ValueKind int
+
+const (
+	KindNumber ValueKind = iota
+	KindNull
+	KindDate
+	KindDuration
+	KindString
+	KindQuantity
+)
+
+func (this ValueKind) String() string {
+	switch this {
+	case KindNumber:
+		return "number"
+	case KindNull:
+		return "null"
+	case KindDate:
+		return "date"
+	case KindDuration:
+		return "duration"
+	case KindString:
+		return "string"
+	case KindQuantity:
+		return "quantity"
+	}
+	return fmt.Sprintf("ValueKind(%d)", int(this))
+}
+
+/*
+	Value is the result of a formula evaluated with 'EvalValue' or 'CalculateValue'.
+
+	Besides a number, it can hold a date ('time.Time'), a duration ('time.Duration'), a string, a
+	number with a unit or be null: a null variable (nil, a nil pointer or an invalid 'sql.NullFloat64') makes the arithmetic
+	operations and the comparisons that use it null, like in SQL.
+*/
+type Value struct {
+	kind     ValueKind
+	number   float64
+	duration time.Duration
+	// the time.Time of a date, the string or the *unit of a quantity
+	data interface{}
+}
+
+/*
+	ErrNullResult is returned by the evaluations that return a float64 when the result of the formula is null.
+	Use 'EvalValue' or 'CalculateValue' to receive null results.
+*/
+var ErrNullResult = errors.New("the result of the formula is null")
+
+var nullValue = Value{kind: KindNull}
+
+/*
+	Create a Value holding the given number.
+*/
+func NumberValue(number float64) Value {
+	return Value{kind: KindNumber, number: number}
+}
+
+/*
+	Create a Value holding the given date.
+*/
+func DateValue(date time.Time) Value {
+	return Value{kind: KindDate, data: date}
+}
+
+/*
+	Create a Value holding the given duration.
+*/
+func DurationValue(duration time.Duration) Value {
+	return Value{kind: KindDuration, duration: duration}
+}
+
+/*
+	Create a Value holding the given string.
+*/
+func StringValue(text string) Value {
+	return Value{kind: KindString, data: text}
+}
+
+/*
+	Create a Value holding a quantity: the given number of [unit] (i.e. "km/h"), which is parsed like
+	the units of the formulas. A dimensionless unit (i.e. "m/km") gives a number.
+	Returns an error if the unit is not valid.
+*/
+func QuantityValue(number float64, unit string) (Value, error) {
+	parsed, err := parseUnit(unit)
+	if err != nil {
+		return nullValue, err
+	}
+	return newQuantity(number, parsed), nil
+}
+
+/*
+	Create a null Value.
+*/
+func NullValue() Value {
+	return nullValue
+}
+
+func (this Value) Kind() ValueKind {
+	return this.kind
+}
+
+func (this Value) IsNull() bool {
+	return this.kind == KindNull
+}
+
+/*
+	Returns the number held by the value and whether it holds one.
+*/
+func (this Value) Float64() (float64, bool) {
+	return this.number, this.kind == KindNumber
+}
+
+/*
+	Returns the date held by the value and whether it holds one.
+*/
+func (this Value) Time() (time.Time, bool) {
+	date, ok := this.data.(time.Time)
+	return date, ok
+}
+
+/*
+	Returns the duration held by the value and whether it holds one.
+*/
+func (this Value) Duration() (time.Duration, bool) {
+	return this.duration, this.kind == KindDuration
+}
+
+/*
+	Returns the number and the unit (i.e. "m/s^2") of the quantity held by the value and whether it holds one.
+*/
+func (this Value) Quantity() (float64, string, bool) {
+	if this.kind != KindQuantity {
+		return 0, "", false
+	}
+	return this.number, this.data.(*unit).String(), true
+}
+
+/*
+	Returns the string held by the value and whether it holds one.
+*/
+func (this Value) Text() (string, bool) {
+	text, ok := this.data.(string)
+	return text, ok
+}
+
+func (this Value) String() string {
+	switch this.kind {
+	case KindNull:
+		return "null"
+	case KindDate:
+		return this.data.(time.Time).Format(time.RFC3339Nano)
+	case KindDuration:
+		return this.duration.String()
+	case KindString:
+		return this.data.(string)
+	case KindQuantity:
+		return strconv.FormatFloat(this.number, 'g', -1, 64) + " " + this.data.(*unit).String()
+	}
+	return strconv.FormatFloat(this.number, 'g', -1, 64)
+}
+
+/*
+	Converts the result of a formula for the evaluations that return a float64: a duration is
+	converted to seconds, a date to the seconds elapsed since January 1, 1970 UTC and a quantity to
+	its number, in its own unit.
+*/
+func (this Value) toFloat64() (float64, error) {
+	switch this.kind {
+	case KindNumber, KindQuantity:
+		return this.number, nil
+	case KindNull:
+		return 0, ErrNullResult
+	case KindDuration:
+		return this.duration.Seconds(), nil
+	case KindDate:
+		return float64(this.data.(time.Time).UnixNano()) / float64(time.Second), nil
+	}
+	return 0, fmt.Errorf("the result of the formula is a %s, use 'EvalValue' or 'CalculateValue' to receive it", this.kind)
+}
+
+// Tells whether both values are of the same kind and hold the same value.
+func (this Value) equals(other Value) bool {
+	if this.kind != other.kind {
+		return false
+	}
+
+	switch this.kind {
+	case KindNumber:
+		return this.number == other.number
+	case KindDuration:
+		return this.duration == other.duration
+	case KindDate:
+		return this.data.(time.Time).Equal(other.data.(time.Time))
+	case KindQuantity:
+		left, right := this.data.(*unit), other.data.(*unit)
+		return left.dimension == right.dimension && this.number*left.scale == other.number*right.scale
+	}
+	return this.data == other.data
+}
+
+func booleanValue(condition bool) Value {
+	if condition {
+		return NumberValue(1.0)
+	}
+	return NumberValue(0.0)
+}
+
+// Tells whether the value is false in the three-valued logic: null is neither true nor false.
+func isFalse(value Value) bool {
+	return value.kind == KindNumber && value.number == 0.0
+}
+
+// Tells whether the value is true in the three-valued logic: null is neither true nor false.
+func isTrue(value Value) bool {
+	return value.kind == KindNumber && value.number != 0.0
+}
+
+/*
+	TypeConverter converts the values of a type, registered with 'WithTypeConverter', into numbers.
+*/
+type TypeConverter func(value interface{}) (float64, error)
+
+// Converts the values of the variables.
+type valueConverter struct {
+	// only the Go numeric types are converted
+	strict     bool
+	converters map[reflect.Type]TypeConverter
+}
+
+/*
+	Converts the value of a variable. nil, nil pointers and the 'driver.Valuer' types returning nil
+	(i.e. an invalid 'sql.NullFloat64') are null, 'time.Time' is a date, 'time.Duration' a duration
+	and a Value is used as it is (i.e. a quantity created with 'QuantityValue').
+*/
+func (this valueConverter) toValue(value interface{}) (Value, error) {
+	if value == nil {
+		return nullValue, nil
+	}
+
+	if converter, found := this.converters[reflect.TypeOf(value)]; found {
+		number, err := converter(value)
+		if err != nil {
+			return nullValue, err
+		}
+		return NumberValue(number), nil
+	}
+
+	reflected := reflect.ValueOf(value)
+	if reflected.Kind() == reflect.Ptr && reflected.IsNil() {
+		return nullValue, nil
+	}
+
+	if valuer, ok := value.(driver.Valuer); ok {
+		driverValue, err := valuer.Value()
+		if err != nil {
+			return nullValue, err
+		}
+		if driverValue == nil {
+			return nullValue, nil
+		}
+		value = driverValue
+		reflected = reflect.ValueOf(value)
+	}
+
+	switch v := value.(type) {
+	case time.Time:
+		return DateValue(v), nil
+	case time.Duration:
+		return DurationValue(v), nil
+	case Value:
+		return v, nil
+	}
+
+	number, err := toFloat64(value)
+	if err == nil || this.strict {
+		return NumberValue(number), err
+	}
+
+	number, err = convertToFloat64(value)
+	if err != nil {
+		if reflected.Kind() == reflect.Ptr {
+			return this.toValue(reflected.Elem().Interface())
+		}
+		return nullValue, err
+	}
+	return NumberValue(number), nil
+}
@@ -0,0 +1,142 @@
+package gojacego
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"math"
+	"math/big"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type money struct {
+	units int64
+	nanos int32
+}
+
+func TestToValue(test *testing.T) {
+	var nilPointer *float64
+	var nilNullFloat *sql.NullFloat64
+
+	scenarios := []struct {
+		value    interface{}
+		expected Value
+	}{
+		{value: nil, expected: NullValue()},
+		{value: nilPointer, expected: NullValue()},
+		{value: nilNullFloat, expected: NullValue()},
+		{value: sql.NullFloat64{}, expected: NullValue()},
+		{value: sql.NullInt64{}, expected: NullValue()},
+		{value: sql.NullFloat64{Float64: 1.5, Valid: true}, expected: NumberValue(1.5)},
+		{value: sql.NullInt64{Int64: 3, Valid: true}, expected: NumberValue(3)},
+		{value: 2, expected: NumberValue(2)},
+		{value: time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC), expected: DateValue(time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC))},
+		{value: 90 * time.Minute, expected: DurationValue(90 * time.Minute)},
+	}
+
+	for _, scenario := range scenarios {
+		value, err := valueConverter{}.toValue(scenario.value)
+		if err != nil {
+			test.Errorf("%#v => unexpected error: %v", scenario.value, err)
+			continue
+		}
+
+		if value != scenario.expected {
+			test.Errorf("%#v => expected: %v, got: %v", scenario.value, scenario.expected, value)
+		}
+	}
+
+	if _, err := (valueConverter{}).toValue("text"); err == nil {
+		test.Errorf("error should not be null")
+	}
+}
+
+func TestValue(test *testing.T) {
+	if number, ok := NumberValue(2.5).Float64(); !ok || number != 2.5 {
+		test.Errorf("expected: 2.5, got: %v", number)
+	}
+
+	if _, ok := NullValue().Float64(); ok {
+		test.Errorf("expected: no number")
+	}
+
+	if NumberValue(2.5).String() != "2.5" || NullValue().String() != "null" {
+		test.Errorf("unexpected text: %s, %s", NumberValue(2.5), NullValue())
+	}
+
+	if !NullValue().IsNull() || NullValue().Kind() != KindNull || NumberValue(math.NaN()).IsNull() {
+		test.Errorf("unexpected kind")
+	}
+}
+
+func TestValueConversion(test *testing.T) {
+	number := 2.5
+	amount := cents(150)
+	pointer := &amount
+	validFloat := sql.NullFloat64{Float64: 4, Valid: true}
+
+	scenarios := []struct {
+		value    interface{}
+		expected float64
+	}{
+		{value: true, expected: 1},
+		{value: false, expected: 0},
+		{value: " 12.5 ", expected: 12.5},
+		{value: "-1e3", expected: -1000},
+		{value: json.Number("42"), expected: 42},
+		{value: big.NewFloat(1.25), expected: 1.25},
+		{value: big.NewRat(1, 4), expected: 0.25},
+		{value: big.NewInt(7), expected: 7},
+		{value: amount, expected: 150},
+		{value: &number, expected: 2.5},
+		{value: &pointer, expected: 150},
+		{value: &validFloat, expected: 4},
+	}
+
+	for _, scenario := range scenarios {
+		value, err := valueConverter{}.toValue(scenario.value)
+		if err != nil || value != NumberValue(scenario.expected) {
+			test.Errorf("%#v => expected: %v, got: %v (%v)", scenario.value, scenario.expected, value, err)
+		}
+
+		if _, err := (valueConverter{strict: true}).toValue(scenario.value); err == nil && scenario.value != &validFloat {
+			test.Errorf("%#v => strict mode should not convert it", scenario.value)
+		}
+	}
+
+	// a duration is converted to a duration, whose float is in seconds
+	value, err := valueConverter{}.toValue(90 * time.Second)
+	if seconds, floatErr := value.toFloat64(); err != nil || floatErr != nil || seconds != 90 {
+		test.Errorf("expected: 90, got: %v (%v, %v)", seconds, err, floatErr)
+	}
+
+	invalid := []interface{}{"12a", "", json.Number("x"), struct{}{}, []float64{1}}
+	for _, value := range invalid {
+		if _, err := (valueConverter{}).toValue(value); err == nil {
+			test.Errorf("%#v => error should not be null", value)
+		}
+	}
+}
+
+func TestTypeConverter(test *testing.T) {
+	converter := valueConverter{strict: true, converters: map[reflect.Type]TypeConverter{
+		reflect.TypeOf(money{}): func(value interface{}) (float64, error) {
+			m := value.(money)
+			if m.nanos < 0 {
+				return 0, errors.New("negative nanos")
+			}
+			return float64(m.units) + float64(m.nanos)/1e9, nil
+		},
+	}}
+
+	value, err := converter.toValue(money{units: 3, nanos: 500000000})
+	if err != nil || value != NumberValue(3.5) {
+		test.Errorf("expected: 3.5, got: %v (%v)", value, err)
+	}
+
+	if _, err := converter.toValue(money{nanos: -1}); err == nil {
+		test.Errorf("error should not be null")
+	}
+}